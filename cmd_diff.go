@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rpoletaev/parsexsd/gen"
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+const diffUsage = `Usage: parsexsd diff [options] <old> <new>
+
+Options:
+  -json            Print changes as JSON
+  -catalog <file>  XML catalog mapping schema locations to local copies,
+                   may be repeated
+
+Prints changes between two releases of schemas, each given by a directory,
+a zip archive or an XSD file: added, removed and renamed types and
+elements, elements and attributes whose type, occurrence or facets changed,
+and added and removed enumeration values. Changes marked with ! are
+breaking for decoders generated from the old release: they fail or lose
+required data on documents of the new one.
+`
+
+func runDiff(args []string) error {
+	var (
+		asJSON   bool
+		catalogs listFlag
+	)
+
+	fs := newFlagSet("diff", diffUsage)
+	fs.BoolVar(&asJSON, "json", false, "Print changes as JSON")
+	fs.Var(&catalogs, "catalog", "XML catalog of local copies of schemas")
+	if err := parseArgs(fs, args, 2); err != nil {
+		return err
+	}
+
+	old, err := buildRelease(fs.Arg(0), catalogs)
+	if err != nil {
+		return err
+	}
+	new, err := buildRelease(fs.Arg(1), catalogs)
+	if err != nil {
+		return err
+	}
+
+	changes := gen.Diff(old, new)
+	breaking := 0
+	for _, c := range changes {
+		if c.Breaking {
+			breaking++
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Old      string       `json:"old"`
+			New      string       `json:"new"`
+			Changes  []gen.Change `json:"changes"`
+			Breaking int          `json:"breaking"`
+		}{fs.Arg(0), fs.Arg(1), changes, breaking})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, c := range changes {
+		mark := " "
+		if c.Breaking {
+			mark = "!"
+		}
+		detail := c.Old + c.New
+		if strings.HasSuffix(c.Kind, "-changed") || strings.HasSuffix(c.Kind, "-renamed") {
+			detail = orNone(c.Old) + " -> " + orNone(c.New)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mark, c.Kind, c.Path, detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%d changes, %d breaking\n", len(changes), breaking)
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// buildRelease returns trees of all root schemas of the release
+func buildRelease(name string, catalogs []string) ([]*xsd.XmlTree, error) {
+	schemas, err := gen.Load([]string{name}, gen.LoadOptions{Catalogs: catalogs})
+	if err != nil {
+		return nil, err
+	}
+
+	// schemas imported by several roots are built once
+	var all []xsd.Schema
+	seen := make(map[string]bool)
+	for _, s := range schemas {
+		for _, schema := range s.Schemas {
+			if !seen[schema.Location] {
+				seen[schema.Location] = true
+				all = append(all, schema)
+			}
+		}
+	}
+	return gen.Build(all, gen.DefaultOptions().BuildOptions), nil
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/rpoletaev/parsexsd/gen"
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+const generateUsage = `Usage: parsexsd generate [options] [<xsd_file|zip_file[#entry]>...]
+
+Options:
+  -config <file>   Project config [default: parsexsd.yaml, parsexsd.yml or
+                   parsexsd.toml of the working directory if it exists]
+  -o <file>        Destination file or directory [default: stdout]
+  -p <package>     Package name [default: $GOPACKAGE or main]
+  -e               Generate exported structs [default: true]
+  -x <prefix>      Struct name prefix [default: ""]
+  -b               Decode base64Binary elements into temp files [default: false]
+  -plugin <dir>    Generate <dir>/<version>/plugin.go and build export.so
+                   plugin next to it, with build options of the plugin
+                   section of the config. The plugin exports Registry
+                   opened by xsd.OpenPlugin
+  -registry        Generate var Registry xsd.TypeRegistry of root elements
+  -version <v>     Version of the schemas [default: detected by the version
+                   attribute of xs:schema, the fixed schemeVersion attribute
+                   or a comment like "version 6.4"]
+  -version-pattern <regexp>
+                   Pattern matching the version in comments by its first
+                   group [default: version (\d+(?:\.\d+)*)]
+  -versions        Generate schemas of every version into the package of the
+                   version, like v6_4, inside of the output directory, and
+                   dispatch.go decoding documents by their root element and
+                   schemeVersion, -import is needed
+  -converters      With -versions generate functions converting structs of
+                   every version to the next one, fields which are not
+                   converted are marked with TODO and listed in a report
+  -catalog <file>  XML catalog mapping schema locations to local copies, may
+                   be repeated
+  -layout <mode>   Split code into a file per schema ("schema"), per element
+                   ("element") or a package per namespace ("namespace")
+                   [default: a file per package]
+  -import <path>   Import path of the output directory, needed by packages of
+                   the namespace layout to import each other
+  -check           Do not write files, fail if files on disk differ from
+                   the generated code
+  -manifest <file> Write manifest of hashes of inputs and outputs, generation
+                   is skipped while they, options and parsexsd do not
+                   change [default: none]
+  -force           Generate and build the plugin even if it is up to date
+  -watch           Keep running and regenerate when any schema reached by
+                   imports and includes, a catalog or a schema added to a
+                   directory bundle changes, errors are reported without
+                   exiting
+
+Generates XML decoding/encoding Go structs for the schemas and all schemas
+imported by them. A zip archive of a schema bundle is read as is, its entry
+point is given like bundle.zip#fcsExport.xsd, by default all schemas of the
+archive which are not imported by others are used. Schemas and options given
+on the command line override the config.
+
+Output is the same for the same schemas and options, so it may be kept in
+git and regenerated by go generate, the package defaults to $GOPACKAGE:
+
+  //go:generate parsexsd generate -o export.go schemas/fcsExport.xsd
+
+and checked to be up to date in CI with -check:
+
+  parsexsd generate -check -o export.go schemas/fcsExport.xsd
+`
+
+func runGenerate(args []string) error {
+	var (
+		configFile, output, repository, pckg, prefix string
+		manifest                                     string
+		layout, importPath                           string
+		version, versionPattern                      string
+		exported, streamBinary, check, versions      bool
+		converters, registry, force, watching        bool
+		catalogs                                     listFlag
+	)
+
+	fs := newFlagSet("generate", generateUsage)
+	fs.StringVar(&configFile, "config", "", "Project config file")
+	fs.StringVar(&output, "o", "", "Name of output file or directory")
+	fs.StringVar(&pckg, "p", "main", "Name of the Go package")
+	fs.StringVar(&prefix, "x", "", "Struct name prefix")
+	fs.BoolVar(&exported, "e", true, "Generate exported structs")
+	fs.BoolVar(&streamBinary, "b", false, "Decode base64Binary elements into temp files")
+	fs.StringVar(&repository, "plugin", "", "Repository of versioned plugin directories")
+	fs.Var(&catalogs, "catalog", "XML catalog of local copies of schemas")
+	fs.BoolVar(&check, "check", false, "Fail if generated files on disk are stale")
+	fs.StringVar(&layout, "layout", "", "Split code into files per schema, element or packages per namespace")
+	fs.StringVar(&importPath, "import", "", "Import path of the output directory")
+	fs.StringVar(&version, "version", "", "Version of the schemas")
+	fs.StringVar(&versionPattern, "version-pattern", "", "Pattern of the version in comments of schemas")
+	fs.BoolVar(&versions, "versions", false, "Generate packages of versions and a dispatcher")
+	fs.BoolVar(&converters, "converters", false, "Generate converters between versions")
+	fs.BoolVar(&registry, "registry", false, "Generate registry of root elements")
+	fs.StringVar(&manifest, "manifest", "", "Manifest of inputs and outputs of the generation")
+	fs.BoolVar(&force, "force", false, "Generate even if the manifest is up to date")
+	fs.BoolVar(&watching, "watch", false, "Regenerate when schemas change")
+	if err := parseArgs(fs, args, -1); err != nil {
+		return err
+	}
+
+	if configFile == "" {
+		configFile = findConfig()
+	}
+	cfg := defaultConfig()
+	if configFile != "" {
+		var err error
+		if cfg, err = loadConfig(configFile); err != nil {
+			return err
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "o":
+			cfg.Output = output
+		case "p":
+			cfg.Package = pckg
+		case "x":
+			cfg.Naming.Prefix = prefix
+		case "e":
+			cfg.Naming.Exported = exported
+		case "b":
+			cfg.Features.StreamBinary = streamBinary
+		case "plugin":
+			cfg.Plugin.Repository = repository
+		case "catalog":
+			cfg.Catalogs = catalogs
+		case "layout":
+			cfg.Layout = layout
+		case "import":
+			cfg.ImportPath = importPath
+		case "version":
+			cfg.Version.Value = version
+		case "version-pattern":
+			cfg.Version.Pattern = versionPattern
+		case "versions":
+			cfg.Version.Packages = versions
+		case "converters":
+			cfg.Version.Converters = converters
+		case "registry":
+			cfg.Features.Registry = registry
+		case "manifest":
+			cfg.Manifest = manifest
+		}
+	})
+	if fs.NArg() > 0 {
+		cfg.Schemas = fs.Args()
+	}
+	if len(cfg.Schemas) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	if check && (cfg.Plugin.Repository != "" || cfg.Output == "" || cfg.Output == "-") {
+		return fmt.Errorf("-check needs output file or directory and does not build plugins")
+	}
+	if cfg.Version.Packages && cfg.Plugin.Repository != "" {
+		return fmt.Errorf("packages of versions are not built as plugins")
+	}
+	if cfg.Version.Converters && !cfg.Version.Packages {
+		return fmt.Errorf("converters are generated between packages of versions, -versions is needed")
+	}
+
+	if watching {
+		if check || (cfg.Plugin.Repository == "" && (cfg.Output == "" || cfg.Output == "-")) {
+			return fmt.Errorf("-watch needs output file or directory or -plugin and does not check files")
+		}
+		return watch(cfg, force)
+	}
+	_, err := generate(cfg, check, force)
+	return err
+}
+
+// summary is the result of a generation, it is logged with fields for CI
+type summary struct {
+	schemas, inputs, files, types int
+	upToDate                      bool
+}
+
+// log logs the summary of the generation finished with the error
+func (s summary) log(err error, elapsed time.Duration) {
+	status := "ok"
+	if err != nil {
+		status = "failed"
+	}
+	log.WithFields(log.Fields{
+		"status":   status,
+		"schemas":  s.schemas,
+		"inputs":   s.inputs,
+		"files":    s.files,
+		"types":    s.types,
+		"warnings": warnings.count(),
+		"upToDate": s.upToDate,
+		"duration": elapsed.Round(time.Millisecond).String(),
+	}).Info("Generation summary")
+}
+
+// generate writes code of the configured schemas and logs the summary, see
+// generateCode. Files the code is generated from are returned even if it
+// is skipped.
+func generate(cfg config, check, force bool) ([]gen.Source, error) {
+	start := time.Now()
+	warnings.reset()
+
+	var sum summary
+	inputs, err := generateCode(cfg, check, force, &sum)
+	sum.inputs = len(inputs)
+	sum.log(err, time.Since(start))
+	return inputs, err
+}
+
+// generateCode writes code of the configured schemas, code of every
+// package goes to a subdirectory of the output named after the package if
+// there are several packages. With check files are compared with the
+// generated code instead. Generation is skipped if the manifest of the
+// output is up to date, unless it is forced.
+func generateCode(cfg config, check, force bool, sum *summary) ([]gen.Source, error) {
+	detectors, err := cfg.versionDetectors()
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest string
+	if !check {
+		manifest = cfg.Manifest
+	}
+	if manifest != "" && !force {
+		if m, err := gen.ReadManifest(manifest); err == nil && m.UpToDate(cfg.cacheKey()) {
+			log.Infof("Generated code is up to date with %s, use -force to regenerate", manifest)
+			sum.upToDate = true
+			return m.Inputs, nil
+		}
+	}
+
+	loadOpts := gen.LoadOptions{Catalogs: cfg.Catalogs, VersionDetectors: detectors}
+	if cfg.Plugin.Repository != "" {
+		loadOpts.VersionFile = cfg.Plugin.VersionFile
+	}
+	schemas, err := gen.Load(cfg.Schemas, loadOpts)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := inputsOf(cfg, schemas)
+	if err != nil {
+		return nil, err
+	}
+
+	generateFiles := gen.Generate
+	if cfg.Version.Packages {
+		generateFiles = gen.GenerateVersions
+	}
+	files, err := generateFiles(schemas, cfg.options())
+	if err != nil {
+		return inputs, fmt.Errorf("code generation failed unexpectedly: %v", err)
+	}
+	sum.schemas = len(schemas)
+	sum.files = len(files)
+	for _, f := range files {
+		sum.types += f.Types
+	}
+
+	if cfg.Plugin.Repository != "" {
+		if len(files) > 1 {
+			return inputs, fmt.Errorf("plugin is built from a single file, code is split into %d", len(files))
+		}
+		version := schemas[0].Version()
+		if version == nil {
+			return inputs, fmt.Errorf("version of %s is not found, give it by -version", schemas[0].Name)
+		}
+		outputs, err := buildPlugin(cfg.Plugin, version, files[0])
+		if err != nil {
+			return inputs, err
+		}
+		return inputs, writeManifest(manifest, cfg, inputs, outputs)
+	}
+
+	var stale, outputs []string
+	for _, f := range files {
+		if !check {
+			if err := writeFile(cfg.Output, f, len(files) > 1); err != nil {
+				return inputs, err
+			}
+			outputs = append(outputs, outputName(cfg.Output, f, len(files) > 1))
+			continue
+		}
+
+		name := outputName(cfg.Output, f, len(files) > 1)
+		if data, err := os.ReadFile(name); err != nil || !bytes.Equal(data, f.Content) {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		return inputs, fmt.Errorf("generated code is stale, run parsexsd generate: %s", strings.Join(stale, ", "))
+	}
+	if check || cfg.Output == "" || cfg.Output == "-" {
+		return inputs, nil
+	}
+	return inputs, writeManifest(manifest, cfg, inputs, outputs)
+}
+
+// inputsOf returns files the schemas and catalogs are read from
+func inputsOf(cfg config, schemas []gen.Schema) ([]gen.Source, error) {
+	var inputs []gen.Source
+	for _, s := range schemas {
+		inputs = append(inputs, s.Sources...)
+	}
+	for _, c := range cfg.Catalogs {
+		src, err := gen.HashFile(c)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, src)
+	}
+	return inputs, nil
+}
+
+// writeManifest records inputs and outputs of the generation, nothing is
+// recorded if the manifest name is empty
+func writeManifest(name string, cfg config, inputs []gen.Source, outputs []string) error {
+	if name == "" {
+		return nil
+	}
+
+	m, err := gen.NewManifest(name, cfg.cacheKey(), inputs, outputs)
+	if err != nil {
+		return err
+	}
+	return m.Write()
+}
+
+// buildPlugin writes code to the directory of the schema version inside of
+// the plugin repository and builds export.so plugin next to it, it returns
+// names of both files
+func buildPlugin(cfg pluginConfig, version xsd.Version, f gen.File) ([]string, error) {
+	pluginDir, err := makePluginDir(cfg.Repository, version)
+	if err != nil {
+		return nil, err
+	}
+
+	source := filepath.Join(pluginDir, "plugin.go")
+	if err := os.WriteFile(source, f.Content, 0666); err != nil {
+		return nil, err
+	}
+
+	compiler := gen.NewPluginCompiler(filepath.Join(pluginDir, "export"), source)
+	compiler.Options = cfg.options()
+	if err := compiler.BuildPlugin(); err != nil {
+		return nil, err
+	}
+	return []string{source, filepath.Join(pluginDir, xsd.PluginFile)}, nil
+}
+
+// makePluginDir creates directory of the schema version inside of the
+// plugin repository
+func makePluginDir(repository string, version xsd.Version) (string, error) {
+	log.WithField("version", version.String()).Debug("Plugin directory of the schema version")
+
+	pluginDir := filepath.Join(repository, version.String())
+	if err := os.MkdirAll(pluginDir, 0777); err != nil {
+		return "", fmt.Errorf("could not create plugin dir: %v", err)
+	}
+	return pluginDir, nil
+}
+
+// writeFile writes the generated file to output. Empty name or "-" means
+// stdout, a directory gets the file under its name. Output of several files
+// must be a directory.
+func writeFile(output string, f gen.File, several bool) error {
+	if output == "" || output == "-" {
+		if several {
+			return fmt.Errorf("code is split into several files, output must be a directory")
+		}
+		_, err := os.Stdout.Write(f.Content)
+		return err
+	}
+
+	name := outputName(output, f, several)
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return fmt.Errorf("could not create output dir: %v", err)
+	}
+	return os.WriteFile(name, f.Content, 0666)
+}
+
+// outputName returns name of the generated file inside of the output
+func outputName(output string, f gen.File, several bool) string {
+	if fi, err := os.Stat(output); (err == nil && fi.IsDir()) || strings.HasSuffix(output, string(filepath.Separator)) || several {
+		return filepath.Join(output, f.Name)
+	}
+	return output
+}
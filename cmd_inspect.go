@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+const inspectUsage = `Usage: parsexsd inspect [options] <xsd_file|zip_file#entry>
+
+Options:
+  -t <name>        Print only the element or type with the name
+  -json            Print types as JSON
+  -catalog <file>  XML catalog mapping schema locations to local copies,
+                   may be repeated
+
+Prints root elements and complex types resolved from the schema and all
+schemas imported by it, with their attributes, children and facets. The
+schema may be an entry of a zip archive of a schema bundle.
+`
+
+func runInspect(args []string) error {
+	var (
+		name     string
+		asJSON   bool
+		catalogs listFlag
+	)
+
+	fs := newFlagSet("inspect", inspectUsage)
+	fs.StringVar(&name, "t", "", "Name of the element or type")
+	fs.BoolVar(&asJSON, "json", false, "Print types as JSON")
+	fs.Var(&catalogs, "catalog", "XML catalog of local copies of schemas")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	s, err := loadSchema(fs.Arg(0), catalogs)
+	if err != nil {
+		return err
+	}
+
+	var trees []*xsd.XmlTree
+	for _, t := range xsd.NewBuilder(s).BuildXML() {
+		if name == "" || t.Name == name {
+			trees = append(trees, t)
+		}
+	}
+	if len(trees) == 0 {
+		return fmt.Errorf("no element or type %q in the schema", name)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(trees)
+	}
+
+	for _, t := range trees {
+		printTree(os.Stdout, t, 0)
+	}
+	return nil
+}
+
+// printTree writes an indented description of the tree, children of named
+// types are not expanded
+func printTree(w io.Writer, t *xsd.XmlTree, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	kind := "type"
+	if t.Root {
+		kind = "element"
+	}
+	if depth == 0 {
+		fmt.Fprintf(w, "%s %s", kind, t.Name)
+		if t.Type != "" && t.Type != t.Name {
+			fmt.Fprintf(w, " %s", t.Type)
+		}
+	} else {
+		typeName := t.Type
+		if t.Cdata {
+			typeName = "struct"
+		}
+		if t.Nillable {
+			typeName = "nillable " + typeName
+		}
+		if t.List {
+			typeName = "[]" + typeName
+		}
+		fmt.Fprintf(w, "%s%s %s", indent, t.Name, typeName)
+		if t.Optional {
+			fmt.Fprint(w, " optional")
+		}
+	}
+	printFacets(w, t.Facets)
+	fmt.Fprintln(w)
+
+	for _, a := range t.Attribs {
+		fmt.Fprintf(w, "%s  @%s %s", indent, a.Name, a.Type)
+		if a.Optional {
+			fmt.Fprint(w, " optional")
+		}
+		printFacets(w, a.Facets)
+		fmt.Fprintln(w)
+	}
+	if t.Cdata {
+		fmt.Fprintf(w, "%s  #text %s\n", indent, t.Type)
+	}
+	for _, c := range t.Constraints {
+		fmt.Fprintf(w, "%s  %s %s selector=%q", indent, c.Kind, c.Name, c.Selector.XPath)
+		for _, f := range c.Fields {
+			fmt.Fprintf(w, " field=%q", f.XPath)
+		}
+		if c.Refer != "" {
+			fmt.Fprintf(w, " refer=%s", c.Refer)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, c := range t.Children {
+		printTree(w, c, depth+1)
+	}
+}
+
+func printFacets(w io.Writer, f *xsd.Facets) {
+	if f == nil || f.String() == "" {
+		return
+	}
+	fmt.Fprint(w, " ", f.String())
+}
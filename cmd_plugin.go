@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/rpoletaev/parsexsd/gen"
+)
+
+const buildPluginUsage = `Usage: parsexsd build-plugin [options] <go_file>
+
+Options:
+  -o <file>        Plugin file [default: export.so next to the source]
+  -tags <tags>     Comma separated build tags
+  -ldflags <flags> Flags of the linker
+  -trimpath        Remove file system paths from the plugin
+  -mod <mode>      Module download mode, like readonly or vendor
+  -env <KEY=VALUE> Variable of go build environment, may be repeated
+  -verify          Load the plugin after it is built by a program built
+                   with the same options [default: true]
+
+Builds a Go plugin from previously generated code. GOFLAGS of the
+environment are respected. Errors of the compiler are printed with the XSD
+element or complex type the failing code is generated from.
+`
+
+func runBuildPlugin(args []string) error {
+	var (
+		output, tags string
+		env          listFlag
+		opts         gen.PluginOptions
+	)
+
+	fs := newFlagSet("build-plugin", buildPluginUsage)
+	fs.StringVar(&output, "o", "", "Name of the plugin file")
+	fs.StringVar(&tags, "tags", "", "Comma separated build tags")
+	fs.StringVar(&opts.LDFlags, "ldflags", "", "Flags of the linker")
+	fs.BoolVar(&opts.TrimPath, "trimpath", false, "Remove file system paths from the plugin")
+	fs.StringVar(&opts.Mod, "mod", "", "Module download mode")
+	fs.Var(&env, "env", "Variable of go build environment")
+	fs.BoolVar(&opts.Verify, "verify", true, "Load the plugin after it is built")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	source := fs.Arg(0)
+	if output == "" {
+		output = filepath.Join(filepath.Dir(source), "export.so")
+	}
+	if tags != "" {
+		opts.Tags = strings.Split(tags, ",")
+	}
+	opts.Env = env
+
+	compiler := gen.NewPluginCompiler(strings.TrimSuffix(output, ".so"), source)
+	compiler.Options = opts
+	return compiler.BuildPlugin()
+}
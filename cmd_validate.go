@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rpoletaev/parsexsd/gen"
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+const validateUsage = `Usage: parsexsd validate -schema <xsd_file|zip_file#entry> <xml_file>
+
+Options:
+  -schema <file>   Schema to validate against
+  -config <file>   Project config, its types and features apply to the
+                   schema [default: parsexsd.yaml, parsexsd.yml or
+                   parsexsd.toml of the working directory if it exists]
+  -catalog <file>  XML catalog mapping schema locations to local copies,
+                   may be repeated
+
+Checks the document structure, attribute and element values against the
+schema and prints found problems with their positions. Exits with status 1
+if the document is invalid.
+`
+
+// errInvalid is returned when the validated document has problems
+var errInvalid = errors.New("document is invalid")
+
+func runValidate(args []string) error {
+	var (
+		schema, configFile string
+		catalogs           listFlag
+	)
+
+	fs := newFlagSet("validate", validateUsage)
+	fs.StringVar(&schema, "schema", "", "Schema to validate against")
+	fs.StringVar(&configFile, "config", "", "Project config file")
+	fs.Var(&catalogs, "catalog", "XML catalog of local copies of schemas")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+	if schema == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	if configFile == "" {
+		configFile = findConfig()
+	}
+	cfg := defaultConfig()
+	if configFile != "" {
+		var err error
+		if cfg, err = loadConfig(configFile); err != nil {
+			return err
+		}
+	}
+
+	s, err := loadSchema(schema, append(cfg.Catalogs, catalogs...))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	v := newValidator(gen.Build(s, cfg.buildOptions()))
+	if err := v.validate(f); err != nil {
+		return err
+	}
+
+	for _, p := range v.problems {
+		fmt.Fprintf(os.Stdout, "%s:%s\n", fs.Arg(0), p)
+	}
+	if len(v.problems) > 0 {
+		return errInvalid
+	}
+	return nil
+}
+
+// validator checks an XML document against trees built from a schema
+type validator struct {
+	roots    map[string]*xsd.XmlTree
+	types    map[string]*xsd.XmlTree
+	d        *xml.Decoder
+	problems []string
+}
+
+func newValidator(trees []*xsd.XmlTree) *validator {
+	v := &validator{
+		roots: make(map[string]*xsd.XmlTree),
+		types: make(map[string]*xsd.XmlTree),
+	}
+	for _, t := range trees {
+		if t.Root {
+			v.roots[t.Name] = t
+		} else {
+			v.types[t.Name] = t
+		}
+	}
+	return v
+}
+
+func (v *validator) validate(r io.Reader) error {
+	v.d = xsd.NewDecoder(r)
+
+	for {
+		tok, err := v.d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			t, ok := v.roots[start.Name.Local]
+			if !ok {
+				v.report("unknown root element %q", start.Name.Local)
+				return v.d.Skip()
+			}
+			return v.element(start, t)
+		}
+	}
+}
+
+func (v *validator) report(format string, args ...interface{}) {
+	line, col := v.d.InputPos()
+	v.problems = append(v.problems, fmt.Sprintf("%d:%d: ", line, col)+fmt.Sprintf(format, args...))
+}
+
+// resolve returns the tree holding content of the element, which is the
+// named complex type for elements declared with a type
+func (v *validator) resolve(t *xsd.XmlTree) *xsd.XmlTree {
+	if !t.StructNeeded {
+		if ct, ok := v.types[t.Type]; ok {
+			return ct
+		}
+	}
+	return t
+}
+
+// element validates the element started by start and consumes it up to the
+// end element
+func (v *validator) element(start xml.StartElement, t *xsd.XmlTree) error {
+	ct := v.resolve(t)
+	name := start.Name.Local
+
+	isNil := false
+	seen := make(map[string]bool)
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+			continue
+		}
+		if a.Name.Space == xsd.XSINamespace || a.Name.Space == "xsi" {
+			if a.Name.Local == "nil" {
+				nilValue, err := xsd.ParseBoolean(a.Value)
+				if err != nil {
+					v.report("attribute xsi:nil of element %q: %v", name, err)
+				}
+				if nilValue {
+					isNil = true
+					if !t.Nillable {
+						v.report("element %q is not nillable", name)
+					}
+				}
+			}
+			continue
+		}
+
+		attr, ok := findAttrib(ct, a.Name.Local)
+		if !ok {
+			v.report("unknown attribute %q of element %q", a.Name.Local, name)
+			continue
+		}
+		seen[attr.Name] = true
+		if err := checkLexical(a.Value, attr.Type, attr.Facets); err != nil {
+			v.report("attribute %q of element %q: %v", attr.Name, name, err)
+		}
+	}
+	for _, a := range ct.Attribs {
+		if !a.Optional && !seen[a.Name] {
+			v.report("element %q has no required attribute %q", name, a.Name)
+		}
+	}
+
+	counts := make(map[string]int)
+	var text strings.Builder
+	for {
+		tok, err := v.d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			child, ok := findChild(ct, tok.Name.Local)
+			if !ok {
+				v.report("unexpected element %q in %q", tok.Name.Local, name)
+				if err := v.d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			counts[child.Name]++
+			if counts[child.Name] == 2 && !child.List {
+				v.report("element %q occurs more than once in %q", child.Name, name)
+			}
+			if err := v.element(tok, child); err != nil {
+				return err
+			}
+		case xml.CharData:
+			text.Write(tok)
+		case xml.EndElement:
+			if isNil {
+				return nil
+			}
+			for _, c := range ct.Children {
+				if counts[c.Name] == 0 && !c.Optional {
+					v.report("element %q has no required element %q", name, c.Name)
+				}
+			}
+			if simpleContent(ct) {
+				if err := checkLexical(text.String(), ct.Type, ct.Facets); err != nil {
+					v.report("element %q: %v", name, err)
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func findAttrib(t *xsd.XmlTree, name string) (xsd.XmlAttrib, bool) {
+	for _, a := range t.Attribs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return xsd.XmlAttrib{}, false
+}
+
+func findChild(t *xsd.XmlTree, name string) (*xsd.XmlTree, bool) {
+	for _, c := range t.Children {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// simpleContent returns true if the element value is character data
+func simpleContent(t *xsd.XmlTree) bool {
+	return t.Cdata || (len(t.Children) == 0 && len(t.Attribs) == 0 && t.Type != "")
+}
+
+// bitSize returns the size of the Go number type for strconv, values out
+// of its range are not decoded by encoding/xml
+func bitSize(typeName string) int {
+	switch typeName {
+	case "int8", "uint8", "byte":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32", "float32":
+		return 32
+	case "int", "uint":
+		return 0
+	}
+	return 64
+}
+
+// checkLexical checks lexical form of a value of Go type the schema type is
+// mapped to, and its facets
+func checkLexical(value, typeName string, facets *xsd.Facets) error {
+	if facets != nil {
+		if err := facets.Check(value); err != nil {
+			return err
+		}
+	}
+
+	value = xsd.NormalizeWhiteSpace(value, xsd.WhiteSpaceCollapse)
+	var err error
+	switch typeName {
+	case "bool":
+		_, err = xsd.ParseBoolean(value)
+	case "int", "int8", "int16", "int32", "int64":
+		_, err = strconv.ParseInt(value, 10, bitSize(typeName))
+	case "uint", "byte", "uint8", "uint16", "uint32", "uint64":
+		_, err = strconv.ParseUint(value, 10, bitSize(typeName))
+	case "float32", "float64":
+		_, err = strconv.ParseFloat(value, bitSize(typeName))
+	case "time.Time":
+		_, err = parseTime(value, "2006-01-02T15:04:05")
+	case "xsd.Date":
+		_, err = parseTime(value, xsd.DefaultXSDDateFormat)
+	case "xsd.Base64Binary", "xsd.Base64Stream":
+		_, err = base64.StdEncoding.DecodeString(strings.Replace(value, " ", "", -1))
+	case "xsd.HexBinary":
+		_, err = hex.DecodeString(value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q", typeName, value)
+	}
+	return nil
+}
+
+// parseTime parses date and time values with optional fraction of seconds
+// and time zone
+func parseTime(value, layout string) (time.Time, error) {
+	for _, l := range []string{layout + "Z07:00", layout + ".999999999Z07:00", layout, layout + ".999999999"} {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
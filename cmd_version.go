@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rpoletaev/parsexsd/gen"
+)
+
+const schemaVersionUsage = `Usage: parsexsd schema-version [options] <dir|zip_file>
+
+Options:
+  -f <file>  Schema file holding the version [default: IntegrationTypes.xsd]
+
+Prints version of the schema bundle in the directory or zip archive. It is
+the version attribute of xs:schema, the fixed value of the schemeVersion
+attribute or a comment like "version 6.4".
+`
+
+func runSchemaVersion(args []string) error {
+	var file string
+
+	fs := newFlagSet("schema-version", schemaVersionUsage)
+	fs.StringVar(&file, "f", "IntegrationTypes.xsd", "Schema file holding the version")
+	if err := parseArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	version, err := gen.ReadVersion(fs.Arg(0), file)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stdout, version)
+	return nil
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/Sirupsen/logrus"
+	"github.com/rpoletaev/parsexsd/gen"
+	"github.com/rpoletaev/parsexsd/xsd"
+	"gopkg.in/yaml.v3"
+)
+
+// configNames are looked up in the working directory when no config file is
+// given
+var configNames = []string{"parsexsd.yaml", "parsexsd.yml", "parsexsd.toml"}
+
+// config is the project configuration of code generation, read from
+// parsexsd.yaml or parsexsd.toml. Relative paths are resolved against the
+// directory of the config file.
+type config struct {
+	// Schemas are the input XSD files
+	Schemas []string `yaml:"schemas" toml:"schemas"`
+	// Catalogs are OASIS XML catalogs mapping schema locations to local
+	// copies
+	Catalogs []string `yaml:"catalogs" toml:"catalogs"`
+	// Output is the destination file or directory
+	Output string `yaml:"output" toml:"output"`
+	// Manifest records hashes of inputs and outputs, generation is skipped
+	// while they, the config and parsexsd do not change, no manifest is
+	// written if it is empty
+	Manifest string `yaml:"manifest" toml:"manifest"`
+	// Package is the Go package of generated code
+	Package string `yaml:"package" toml:"package"`
+	// Namespaces maps target namespaces of input schemas to Go packages,
+	// which are generated into subdirectories of Output
+	Namespaces map[string]string `yaml:"namespaces" toml:"namespaces"`
+	// Layout splits code into a file per schema, per element or a package
+	// per namespace, see gen.Layout constants
+	Layout string `yaml:"layout" toml:"layout"`
+	// ImportPath is the import path of Output, packages of the namespace
+	// layout import each other by it
+	ImportPath string `yaml:"importPath" toml:"importPath"`
+	// Types maps XSD type names, qualified like {namespace}name or local, to
+	// Go types. Types of other packages are given with import path, e.g.
+	// github.com/google/uuid.UUID
+	Types map[string]string `yaml:"types" toml:"types"`
+
+	Naming   namingConfig   `yaml:"naming" toml:"naming"`
+	Features featuresConfig `yaml:"features" toml:"features"`
+	Version  versionConfig  `yaml:"version" toml:"version"`
+	Plugin   pluginConfig   `yaml:"plugin" toml:"plugin"`
+}
+
+type namingConfig struct {
+	// Prefix of generated struct names
+	Prefix string `yaml:"prefix" toml:"prefix"`
+	// Exported makes generated structs exported
+	Exported bool `yaml:"exported" toml:"exported"`
+	// Initialisms are additional spellings of name parts, e.g. Inn: INN
+	Initialisms map[string]string `yaml:"initialisms" toml:"initialisms"`
+}
+
+type featuresConfig struct {
+	// StreamBinary decodes base64Binary elements into temp files
+	StreamBinary bool `yaml:"streamBinary" toml:"streamBinary"`
+	// Validate generates Validate methods
+	Validate bool `yaml:"validate" toml:"validate"`
+	// Nillable wraps nillable elements into xsd.Nillable
+	Nillable bool `yaml:"nillable" toml:"nillable"`
+	// Registry generates xsd.TypeRegistry of root elements, it is always
+	// generated into plugins
+	Registry bool `yaml:"registry" toml:"registry"`
+}
+
+type versionConfig struct {
+	// Value is the version of schemas, it is detected if it is empty
+	Value string `yaml:"value" toml:"value"`
+	// Pattern matches the version in comments of schemas by its first
+	// group
+	Pattern string `yaml:"pattern" toml:"pattern"`
+	// Attribute is the attribute with the version as the fixed value, it
+	// holds versions of documents read by the dispatcher of Packages
+	Attribute string `yaml:"attribute" toml:"attribute"`
+	// Packages generates schemas of every version into the package of the
+	// version, like v6_4, and a dispatcher of documents to them
+	Packages bool `yaml:"packages" toml:"packages"`
+	// Converters generates functions converting structs of every version
+	// of Packages to the next one
+	Converters bool `yaml:"converters" toml:"converters"`
+}
+
+type pluginConfig struct {
+	// Repository of versioned plugin directories, plugin is not built if
+	// it is empty
+	Repository string `yaml:"repository" toml:"repository"`
+	// VersionFile is the schema the version is read from, relative to
+	// the directory of the first schema, by default it is the version
+	// detected for the schemas
+	VersionFile string `yaml:"versionFile" toml:"versionFile"`
+	// Tags are build tags of the plugin
+	Tags []string `yaml:"tags" toml:"tags"`
+	// LDFlags are flags of the linker
+	LDFlags string `yaml:"ldflags" toml:"ldflags"`
+	// TrimPath removes file system paths from the plugin
+	TrimPath bool `yaml:"trimpath" toml:"trimpath"`
+	// Mod is the module download mode of go build, like readonly or vendor
+	Mod string `yaml:"mod" toml:"mod"`
+	// Env are KEY=VALUE variables of go build, like GOFLAGS=-mod=mod
+	Env []string `yaml:"env" toml:"env"`
+	// Verify loads the plugin after it is built by a program built with
+	// the same options
+	Verify bool `yaml:"verify" toml:"verify"`
+}
+
+// options returns options of the toolchain building the plugin
+func (c pluginConfig) options() gen.PluginOptions {
+	return gen.PluginOptions{
+		Tags:     c.Tags,
+		LDFlags:  c.LDFlags,
+		TrimPath: c.TrimPath,
+		Mod:      c.Mod,
+		Env:      c.Env,
+		Verify:   c.Verify,
+	}
+}
+
+// defaultConfig returns settings used when there is no config file
+func defaultConfig() config {
+	opts := gen.DefaultOptions()
+	// go generate runs commands with the package of the file
+	if pkg := os.Getenv("GOPACKAGE"); pkg != "" {
+		opts.Package = pkg
+	}
+	return config{
+		Package: opts.Package,
+		Naming: namingConfig{
+			Exported: opts.Exported,
+		},
+		Features: featuresConfig{
+			Validate: opts.Validate,
+			Nillable: opts.Nillable,
+		},
+		Version: versionConfig{
+			Attribute: opts.VersionAttribute,
+		},
+		Plugin: pluginConfig{
+			Verify: true,
+		},
+	}
+}
+
+// findConfig returns the config file of the working directory, or empty
+// string if there is none
+func findConfig() string {
+	for _, name := range configNames {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadConfig reads the config file over the defaults
+func loadConfig(name string) (config, error) {
+	cfg := defaultConfig()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return cfg, err
+	}
+
+	switch filepath.Ext(name) {
+	case ".toml":
+		err = toml.Unmarshal(data, &cfg)
+	default:
+		err = yaml.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("could not parse config %s: %v", name, err)
+	}
+
+	for t := range cfg.Types {
+		if !strings.HasPrefix(t, "{") && strings.Contains(t, ":") {
+			return cfg, fmt.Errorf("type %s of config %s has a prefix, qualify it like {namespace}name", t, name)
+		}
+	}
+
+	dir := filepath.Dir(name)
+	for i, s := range cfg.Schemas {
+		cfg.Schemas[i] = resolvePath(dir, s)
+	}
+	for i, c := range cfg.Catalogs {
+		cfg.Catalogs[i] = resolvePath(dir, c)
+	}
+	cfg.Output = resolvePath(dir, cfg.Output)
+	cfg.Manifest = resolvePath(dir, cfg.Manifest)
+	cfg.Plugin.Repository = resolvePath(dir, cfg.Plugin.Repository)
+	return cfg, nil
+}
+
+// cacheKey returns the config with paths relative to the manifest and the
+// build of parsexsd, code is regenerated if any of them changes
+func (c config) cacheKey() interface{} {
+	dir, err := filepath.Abs(filepath.Dir(c.Manifest))
+	if err != nil {
+		dir = filepath.Dir(c.Manifest)
+	}
+	rel := func(name string) string {
+		if name == "" || name == "-" {
+			return name
+		}
+		if abs, err := filepath.Abs(name); err == nil {
+			if r, err := filepath.Rel(dir, abs); err == nil {
+				return filepath.ToSlash(r)
+			}
+		}
+		return name
+	}
+
+	c.Schemas = append([]string(nil), c.Schemas...)
+	for i, s := range c.Schemas {
+		c.Schemas[i] = rel(s)
+	}
+	c.Catalogs = append([]string(nil), c.Catalogs...)
+	for i, s := range c.Catalogs {
+		c.Catalogs[i] = rel(s)
+	}
+	c.Output = rel(c.Output)
+	c.Manifest = rel(c.Manifest)
+	c.Plugin.Repository = rel(c.Plugin.Repository)
+
+	return struct {
+		Config    config
+		Generator string
+	}{c, generatorHash()}
+}
+
+var (
+	generatorOnce sync.Once
+	generator     string
+)
+
+// generatorHash returns the hash of the parsexsd executable, versions of
+// builds are "(devel)" unless they are installed by go install of a
+// release, so they do not tell builds apart
+func generatorHash() string {
+	generatorOnce.Do(func() {
+		generator = "unknown"
+		name, err := os.Executable()
+		if err != nil {
+			log.Warnf("Could not find the executable of parsexsd: %v", err)
+			return
+		}
+		src, err := gen.HashFile(name)
+		if err != nil {
+			log.Warnf("Could not hash the executable of parsexsd: %v", err)
+			return
+		}
+		generator = src.Hash
+	})
+	return generator
+}
+
+func resolvePath(dir, name string) string {
+	if name == "" || name == "-" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// versionDetectors returns detectors of versions of schemas, the version
+// given explicitly wins over all of them
+func (c config) versionDetectors() ([]xsd.VersionDetector, error) {
+	if c.Version.Value != "" {
+		v, err := xsd.ParseVersion(c.Version.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []xsd.VersionDetector{xsd.FixedVersion(v)}, nil
+	}
+
+	comment := xsd.VersionComment{}
+	if c.Version.Pattern != "" {
+		re, err := regexp.Compile(c.Version.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version pattern: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("version pattern %q has no group", c.Version.Pattern)
+		}
+		comment.Pattern = re
+	}
+
+	detectors := []xsd.VersionDetector{xsd.VersionAttribute{}}
+	if c.Version.Attribute != "" {
+		detectors = append(detectors, xsd.VersionFixedAttribute{Name: c.Version.Attribute})
+	}
+	return append(detectors, comment), nil
+}
+
+// buildOptions returns options of building trees of the schemas
+func (c config) buildOptions() gen.BuildOptions {
+	return gen.BuildOptions{
+		StreamBinary: c.Features.StreamBinary,
+		Nillable:     c.Features.Nillable,
+		Types:        c.Types,
+	}
+}
+
+// options returns options of code generation
+func (c config) options() gen.Options {
+	return gen.Options{
+		BuildOptions: c.buildOptions(),
+
+		Package:     c.Package,
+		Namespaces:  c.Namespaces,
+		Layout:      c.Layout,
+		ImportPath:  c.ImportPath,
+		Prefix:      c.Naming.Prefix,
+		Exported:    c.Naming.Exported,
+		Validate:    c.Features.Validate,
+		Initialisms: c.Naming.Initialisms,
+
+		VersionAttribute: c.Version.Attribute,
+		Converters:       c.Version.Converters,
+		Registry:         c.Features.Registry || c.Plugin.Repository != "",
+	}
+}
@@ -0,0 +1,231 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Source is a file with the hex SHA-256 of its content
+type Source struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+}
+
+// HashFile returns the source of the file. A directory bundle is hashed by
+// the listing of its schemas, so the hash changes when a schema, which may
+// be a new root of the bundle, is added or removed.
+func HashFile(name string) (Source, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Source{}, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		if err := hashListing(hash, name); err != nil {
+			return Source{}, err
+		}
+	} else if _, err := io.Copy(hash, f); err != nil {
+		return Source{}, err
+	}
+	return Source{Path: name, Hash: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// hashListing writes sorted slash paths of schemas of the directory tree
+// relative to it into the hash
+func hashListing(hash io.Writer, dir string) error {
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), ".xsd") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(hash, name)
+	}
+	return nil
+}
+
+// Manifest records inputs and outputs of a generation. Its key is the hash
+// of the inputs and options, generation is up to date while the key and
+// hashes of outputs do not change. Paths of local files are recorded
+// relative to the directory of the manifest file, so it stays valid when
+// the directory is moved, and are resolved against it when it is read.
+type Manifest struct {
+	Key     string   `json:"key"`
+	Inputs  []Source `json:"inputs"`
+	Outputs []Source `json:"outputs"`
+
+	// name is the manifest file
+	name string
+}
+
+// NewManifest returns the manifest to be written to the file name, which
+// records the inputs generated with the options, marshalled to JSON for the
+// key, and the output files
+func NewManifest(name string, options interface{}, inputs []Source, outputs []string) (*Manifest, error) {
+	m := &Manifest{Inputs: dedupSources(inputs), name: name}
+	var err error
+	if m.Key, err = m.key(options, m.Inputs); err != nil {
+		return nil, err
+	}
+	for _, output := range outputs {
+		src, err := HashFile(output)
+		if err != nil {
+			return nil, err
+		}
+		m.Outputs = append(m.Outputs, src)
+	}
+	return m, nil
+}
+
+// ReadManifest reads the manifest file
+func ReadManifest(name string) (*Manifest, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{name: name}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("could not read manifest %s: %v", name, err)
+	}
+	for i := range m.Inputs {
+		m.Inputs[i].Path = m.resolve(m.Inputs[i].Path)
+	}
+	for i := range m.Outputs {
+		m.Outputs[i].Path = m.resolve(m.Outputs[i].Path)
+	}
+	return m, nil
+}
+
+// Write writes the manifest file
+func (m *Manifest) Write() error {
+	data, err := json.MarshalIndent(Manifest{
+		Key:     m.Key,
+		Inputs:  m.relative(m.Inputs),
+		Outputs: m.relative(m.Outputs),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.name, append(data, '\n'), 0666)
+}
+
+// dir returns the absolute directory of the manifest file
+func (m *Manifest) dir() string {
+	dir, err := filepath.Abs(filepath.Dir(m.name))
+	if err != nil {
+		return filepath.Dir(m.name)
+	}
+	return dir
+}
+
+// relative returns the sources with paths of local files relative to the
+// directory of the manifest, in slash form
+func (m *Manifest) relative(sources []Source) []Source {
+	dir := m.dir()
+	res := make([]Source, len(sources))
+	for i, src := range sources {
+		res[i] = src
+		if isURL(src.Path) {
+			continue
+		}
+		if abs, err := filepath.Abs(src.Path); err == nil {
+			if rel, err := filepath.Rel(dir, abs); err == nil {
+				res[i].Path = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return res
+}
+
+// resolve returns the path of the file recorded relative to the directory
+// of the manifest
+func (m *Manifest) resolve(path string) string {
+	if isURL(path) || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(m.dir(), filepath.FromSlash(path))
+}
+
+// isURL reports whether the source is not a local file, like a schema read
+// over HTTP
+func isURL(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+// UpToDate reports whether inputs and options are the same as recorded and
+// outputs are not changed. Files are hashed again, schemas are not parsed,
+// so inputs and options are checked before loading them. Inputs which are
+// not local files are never up to date.
+func (m *Manifest) UpToDate(options interface{}) bool {
+	inputs := make([]Source, len(m.Inputs))
+	for i, in := range m.Inputs {
+		src, err := HashFile(in.Path)
+		if err != nil {
+			return false
+		}
+		inputs[i] = src
+	}
+	if key, err := m.key(options, inputs); err != nil || key != m.Key {
+		return false
+	}
+
+	for _, out := range m.Outputs {
+		if src, err := HashFile(out.Path); err != nil || src.Hash != out.Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns the hash of the options and the inputs, paths of which are
+// relative to the manifest
+func (m *Manifest) key(options interface{}, inputs []Source) (string, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write(data)
+	for _, in := range m.relative(inputs) {
+		fmt.Fprintf(hash, "\n%s\x00%s", in.Path, in.Hash)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// dedupSources returns sources sorted by path, each of them once
+func dedupSources(sources []Source) []Source {
+	sorted := append([]Source(nil), sources...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+
+	var res []Source
+	for i, src := range sorted {
+		if i == 0 || src.Path != sorted[i-1].Path {
+			res = append(res, src)
+		}
+	}
+	return res
+}
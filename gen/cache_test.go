@@ -0,0 +1,208 @@
+package gen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifestUpToDate(t *testing.T) {
+	type options struct{ Package string }
+
+	tests := []struct {
+		name    string
+		change  func(dir string) error
+		options options
+		want    bool
+	}{
+		{
+			name:    "same",
+			change:  func(string) error { return nil },
+			options: options{"export"},
+			want:    true,
+		},
+		{
+			name:    "options changed",
+			change:  func(string) error { return nil },
+			options: options{"other"},
+		},
+		{
+			name: "input changed",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "schemas", "a.xsd"), []byte("<changed/>"), 0666)
+			},
+			options: options{"export"},
+		},
+		{
+			name: "input removed",
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "schemas", "a.xsd"))
+			},
+			options: options{"export"},
+		},
+		{
+			name: "output changed",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "export.go"), []byte("package changed\n"), 0666)
+			},
+			options: options{"export"},
+		},
+		{
+			name: "output removed",
+			change: func(dir string) error {
+				return os.Remove(filepath.Join(dir, "export.go"))
+			},
+			options: options{"export"},
+		},
+		{
+			name: "schema added",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "schemas", "b.xsd"), []byte("<schema/>"), 0666)
+			},
+			options: options{"export"},
+		},
+		{
+			name: "other file added",
+			change: func(dir string) error {
+				return os.WriteFile(filepath.Join(dir, "schemas", "readme.txt"), []byte("schemas"), 0666)
+			},
+			options: options{"export"},
+			want:    true,
+		},
+		{
+			name: "directory moved",
+			change: func(dir string) error {
+				return os.Rename(dir, dir+".moved")
+			},
+			options: options{"export"},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(t.TempDir(), "project")
+		input := filepath.Join(dir, "schemas", "a.xsd")
+		output := filepath.Join(dir, "export.go")
+		manifest := filepath.Join(dir, ".parsexsd.json")
+		if err := os.MkdirAll(filepath.Dir(input), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(input, []byte("<schema/>"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(output, []byte("package export\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+
+		var sources []Source
+		for _, name := range []string{input, filepath.Dir(input)} {
+			src, err := HashFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sources = append(sources, src)
+		}
+		m, err := NewManifest(manifest, options{"export"}, sources, []string{output})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Write(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tt.change(dir); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			dir += ".moved"
+			manifest = filepath.Join(dir, ".parsexsd.json")
+		}
+		m, err = ReadManifest(manifest)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := m.UpToDate(tt.options); got != tt.want {
+			t.Errorf("%s: UpToDate() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestManifestRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out", "export.go")
+	if err := os.MkdirAll(filepath.Dir(output), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(output, []byte("package export\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []Source{
+		{Path: filepath.Join(dir, "schemas", "a.xsd"), Hash: "a"},
+		{Path: "https://example.com/b.xsd", Hash: "b"},
+	}
+	manifest := filepath.Join(dir, "out", ".parsexsd.json")
+	m, err := NewManifest(manifest, nil, inputs, []string{output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recorded Manifest
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"../schemas/a.xsd", "https://example.com/b.xsd"} {
+		if got := recorded.Inputs[i].Path; got != want {
+			t.Errorf("input %d is recorded as %s, want %s", i, got, want)
+		}
+		if got := read.Inputs[i].Path; got != inputs[i].Path {
+			t.Errorf("input %d is read as %s, want %s", i, got, inputs[i].Path)
+		}
+	}
+	if got := recorded.Outputs[0].Path; got != "export.go" {
+		t.Errorf("output is recorded as %s, want export.go", got)
+	}
+}
+
+func TestManifestBundle(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "schemas")
+	if err := os.MkdirAll(filepath.Join(bundle, "b"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	writeSchema(t, bundle, "a.xsd", `<xs:element name="a" type="xs:string"/>`)
+
+	schemas, err := Load([]string{bundle}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []Source
+	for _, s := range schemas {
+		inputs = append(inputs, s.Sources...)
+	}
+	m, err := NewManifest(filepath.Join(dir, ".parsexsd.json"), nil, inputs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.UpToDate(nil) {
+		t.Fatal("UpToDate() = false right after generation")
+	}
+
+	// a new root schema of the bundle is not an input yet
+	writeSchema(t, filepath.Join(bundle, "b"), "c.xsd", `<xs:element name="c" type="xs:string"/>`)
+	if m.UpToDate(nil) {
+		t.Error("UpToDate() = true after a schema is added to the bundle")
+	}
+}
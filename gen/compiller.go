@@ -0,0 +1,314 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// PluginOptions are options of the toolchain building plugins
+type PluginOptions struct {
+	// Tags are build tags
+	Tags []string
+	// LDFlags are flags of the linker
+	LDFlags string
+	// TrimPath removes file system paths from the plugin
+	TrimPath bool
+	// Mod is the module download mode, like mod, readonly or vendor
+	Mod string
+	// Env are KEY=VALUE variables added to the environment of go build,
+	// GOFLAGS of the environment is respected
+	Env []string
+	// Dir is the directory go build runs in, the module of the directory
+	// resolves imports of the plugin
+	Dir string
+	// Verify loads the plugin after it is built by a program built with
+	// the same options, as plugins are loaded only by programs built with
+	// the same toolchain flags
+	Verify bool
+}
+
+// PluginCompiler builds Go plugins from generated code
+type PluginCompiler struct {
+	Options PluginOptions
+
+	pluginName string
+	sourcePath string
+}
+
+// NewPluginCompiler creates and returns new pluginCompiler
+func NewPluginCompiler(plugName, pathToSource string) *PluginCompiler {
+	return &PluginCompiler{
+		pluginName: plugName + ".so",
+		sourcePath: pathToSource,
+	}
+}
+
+// BuildPlugin builds the plugin, failures of the compiler are returned as
+// BuildError
+func (p *PluginCompiler) BuildPlugin() error {
+	log.Infof("Building plugin %s from %s", p.pluginName, p.sourcePath)
+
+	args := append([]string{"-buildmode=plugin"}, p.flags()...)
+	output, err := p.goBuild(append(args, "-o", absPath(p.pluginName), absPath(p.sourcePath))...)
+	if err != nil {
+		return &BuildError{
+			Err:         err,
+			Output:      output,
+			Diagnostics: p.diagnostics(output),
+		}
+	}
+
+	if p.Options.Verify {
+		if err := p.verify(); err != nil {
+			return fmt.Errorf("plugin %s is built but can not be opened: %v", p.pluginName, err)
+		}
+	}
+	return nil
+}
+
+// verifySource is the program loading the plugin given as its argument
+const verifySource = `package main
+
+import (
+	"fmt"
+	"os"
+	"plugin"
+)
+
+func main() {
+	if _, err := plugin.Open(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
+
+// verify loads the plugin by a program built with the options of the
+// plugin. It is not loaded by this process, which is built with other
+// flags and caches plugins by their paths.
+func (p *PluginCompiler) verify() error {
+	dir, err := os.MkdirTemp("", "parsexsd-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(source, []byte(verifySource), 0644); err != nil {
+		return err
+	}
+	loader := filepath.Join(dir, "verify")
+	if output, err := p.goBuild(append(p.flags(), "-o", loader, source)...); err != nil {
+		return fmt.Errorf("could not build the loader: %v\n%s", err, strings.TrimSpace(output))
+	}
+
+	output, err := exec.Command(loader, absPath(p.pluginName)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// goBuild runs go build with the arguments in the directory and the
+// environment of the options and returns its output
+func (p *PluginCompiler) goBuild(args ...string) (string, error) {
+	cmd := exec.Command("go", append([]string{"build"}, args...)...)
+	cmd.Dir = p.Options.Dir
+	cmd.Env = append(os.Environ(), p.Options.Env...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return output.String(), err
+}
+
+// flags returns flags of go build given by the options
+func (p *PluginCompiler) flags() []string {
+	var args []string
+	if len(p.Options.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(p.Options.Tags, ","))
+	}
+	if p.Options.LDFlags != "" {
+		args = append(args, "-ldflags", p.Options.LDFlags)
+	}
+	if p.Options.TrimPath {
+		args = append(args, "-trimpath")
+	}
+	if p.Options.Mod != "" {
+		args = append(args, "-mod="+p.Options.Mod)
+	}
+	return args
+}
+
+// absPath keeps paths valid when go build runs in another directory
+func absPath(name string) string {
+	if abs, err := filepath.Abs(name); err == nil {
+		return abs
+	}
+	return name
+}
+
+// BuildError is a failed build of a plugin
+type BuildError struct {
+	Err    error
+	Output string
+	// Diagnostics are errors reported by the compiler
+	Diagnostics []Diagnostic
+}
+
+func (e *BuildError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return fmt.Sprintf("go build failed: %v\n%s", e.Err, strings.TrimSpace(e.Output))
+	}
+
+	lines := []string{fmt.Sprintf("go build failed: %v", e.Err)}
+	for _, d := range e.Diagnostics {
+		lines = append(lines, d.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the error of go build
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostic is an error reported by the compiler at a position of the
+// source
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	// Component is the XSD component the code at the position is generated
+	// from, like element export, it is empty if it is not known
+	Component string
+}
+
+func (d Diagnostic) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+	if d.Component != "" {
+		s += " (XSD " + d.Component + ")"
+	}
+	return s
+}
+
+// diagnosticLine matches errors of the compiler like
+// plugin.go:12:5: undefined: Foo
+var diagnosticLine = regexp.MustCompile(`^(.+\.go):(\d+):(?:(\d+):)? (.+)$`)
+
+// diagnostics parses errors of the compiler and maps ones of the source to
+// XSD components by comments of generated structs
+func (p *PluginCompiler) diagnostics(output string) []Diagnostic {
+	var components *componentMap
+	var res []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		m := diagnosticLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		d := Diagnostic{File: m[1], Message: m[4]}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+
+		if filepath.Base(d.File) == filepath.Base(p.sourcePath) {
+			if components == nil {
+				components = parseComponents(p.sourcePath)
+			}
+			d.Component = components.at(d.Line)
+		}
+		res = append(res, d)
+	}
+	return res
+}
+
+// componentMap maps lines of generated code to XSD components of structs
+// and their methods
+type componentMap struct {
+	decls []componentDecl
+}
+
+type componentDecl struct {
+	from, to  int
+	component string
+}
+
+// parseComponents reads components from doc comments of generated structs
+// like "Export is generated from the XSD element export"
+func parseComponents(source string) *componentMap {
+	res := new(componentMap)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, source, nil, parser.ParseComments)
+	if f == nil {
+		log.Warnf("Could not parse %s: %v", source, err)
+		return res
+	}
+
+	byType := make(map[string]string)
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE || gd.Doc == nil {
+			continue
+		}
+		const marker = "is generated from the XSD "
+		doc := gd.Doc.Text()
+		if i := strings.Index(doc, marker); i >= 0 {
+			for _, spec := range gd.Specs {
+				byType[spec.(*ast.TypeSpec).Name.Name] = strings.TrimSpace(doc[i+len(marker):])
+			}
+		}
+	}
+
+	for _, decl := range f.Decls {
+		var name string
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok == token.TYPE && len(decl.Specs) > 0 {
+				name = decl.Specs[0].(*ast.TypeSpec).Name.Name
+			}
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = receiverType(decl.Recv.List[0].Type)
+			}
+		}
+		if component, ok := byType[name]; ok {
+			res.decls = append(res.decls, componentDecl{
+				from:      fset.Position(decl.Pos()).Line,
+				to:        fset.Position(decl.End()).Line,
+				component: component,
+			})
+		}
+	}
+	return res
+}
+
+func receiverType(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// at returns the component of the code at the line
+func (m *componentMap) at(line int) string {
+	for _, d := range m.decls {
+		if d.from <= line && line <= d.to {
+			return d.component
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	const source = `package main
+
+import "github.com/rpoletaev/parsexsd/xsd"
+
+// Export is generated from the XSD element export
+type Export struct {
+	ID Undefined
+}
+
+// Validate checks Export against facets of the schema
+func (v *Export) Validate() error {
+	return xsd.Missing
+}
+
+// ContractType is generated from the XSD complex type contractType
+type ContractType struct {
+	Number string
+}
+
+func helper() {}
+`
+	dir := t.TempDir()
+	name := filepath.Join(dir, "export.go")
+	if err := os.WriteFile(name, []byte(source), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	const output = `# command-line-arguments
+./export.go:7:5: undefined: Undefined
+./export.go:12:13: undefined: xsd.Missing
+./export.go:17:2: invalid recursive type ContractType
+./export.go:20:6: helper declared and not used
+other.go:3: syntax error
+note: module requires Go 1.22
+`
+	got := NewPluginCompiler("export", name).diagnostics(output)
+	want := []Diagnostic{
+		{File: "./export.go", Line: 7, Column: 5, Message: "undefined: Undefined", Component: "element export"},
+		{File: "./export.go", Line: 12, Column: 13, Message: "undefined: xsd.Missing", Component: "element export"},
+		{File: "./export.go", Line: 17, Column: 2, Message: "invalid recursive type ContractType", Component: "complex type contractType"},
+		{File: "./export.go", Line: 20, Column: 6, Message: "helper declared and not used"},
+		{File: "other.go", Line: 3, Message: "syntax error"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics() = %+v, want %+v", got, want)
+	}
+
+	err := &BuildError{Diagnostics: got[:1]}
+	if !strings.Contains(err.Error(), "./export.go:7:5: undefined: Undefined (XSD element export)") {
+		t.Errorf("BuildError is %q", err)
+	}
+}
+
+func TestParseComponentsInvalid(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "broken.go")
+	if err := os.WriteFile(name, []byte("package main\n\n// Export is generated from the XSD element export\ntype Export struct {"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if c := parseComponents(name).at(4); c != "" {
+		t.Errorf("component of unparsed source is %s", c)
+	}
+	if c := parseComponents(filepath.Join(t.TempDir(), "missing.go")).at(1); c != "" {
+		t.Errorf("component of missing source is %s", c)
+	}
+}
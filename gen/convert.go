@@ -0,0 +1,210 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+	"golang.org/x/tools/imports"
+)
+
+// versionStructs are structs generated into the package of a version
+type versionStructs struct {
+	pkg   string
+	names []string
+	trees map[string]*xsd.XmlTree
+}
+
+// collectStructs returns structs generated from the trees by their element
+// or type names, in the order of generation. Structs of the same name are
+// generated once, from the first tree.
+func collectStructs(pkg string, trees []*xsd.XmlTree) versionStructs {
+	s := versionStructs{pkg: pkg, trees: make(map[string]*xsd.XmlTree)}
+	var visit func(t *xsd.XmlTree)
+	visit = func(t *xsd.XmlTree) {
+		if _, ok := s.trees[t.Name]; ok {
+			return
+		}
+		s.trees[t.Name] = t
+		s.names = append(s.names, t.Name)
+		for _, c := range t.Children {
+			if !primitiveType(c) && c.StructNeeded {
+				visit(c)
+			}
+		}
+	}
+	for _, t := range trees {
+		visit(t)
+	}
+	return s
+}
+
+// structField is a field of a generated struct
+type structField struct {
+	name string
+	// typ is the type of the field in the schema, struct is set for fields
+	// of generated structs
+	typ      string
+	struc    string
+	list     bool
+	nillable bool
+}
+
+func (f structField) String() string {
+	res := f.typ
+	if f.nillable {
+		res = "nillable " + res
+	}
+	if f.list {
+		res = "[]" + res
+	}
+	return res
+}
+
+// fields returns fields of the struct generated from the tree, in the order
+// of generation
+func (s versionStructs) fields(g generator, t *xsd.XmlTree) []structField {
+	var res []structField
+	for _, a := range t.Attribs {
+		res = append(res, structField{name: g.lintTitle(a.Name), typ: a.Type})
+	}
+	for _, c := range t.Children {
+		f := structField{name: g.lintTitle(c.Name), typ: fieldType(c), list: c.List, nillable: c.Nillable}
+		if _, ok := s.trees[f.typ]; ok && !primitiveType(c) {
+			f.struc = f.typ
+		}
+		res = append(res, f)
+	}
+	if t.Cdata {
+		res = append(res, structField{name: g.lintTitle(t.Name), typ: t.Type})
+	}
+	return res
+}
+
+// ConvertReport is a field of a struct which is not converted between
+// versions
+type ConvertReport struct {
+	Struct string
+	Field  string
+	// Kind is added, removed or changed
+	Kind     string
+	Old, New string
+}
+
+func (r ConvertReport) String() string {
+	switch r.Kind {
+	case "added":
+		return fmt.Sprintf("%s.%s is added: %s", r.Struct, r.Field, r.New)
+	case "removed":
+		return fmt.Sprintf("%s.%s is removed: %s", r.Struct, r.Field, r.Old)
+	}
+	return fmt.Sprintf("%s.%s changed type: %s -> %s", r.Struct, r.Field, r.Old, r.New)
+}
+
+// converter generates functions converting structs of the old version to
+// the new one
+type converter struct {
+	g                generator
+	old, new         versionStructs
+	oldPath, newPath string
+	suffix           string
+	body             bytes.Buffer
+	report           []ConvertReport
+}
+
+// convertFile returns file of functions converting structs present in both
+// versions and the report of fields left for manual conversion, which are
+// marked with TODO in the code
+func (g generator) convertFile(old, new versionStructs, importPath string) (File, []ConvertReport, error) {
+	c := converter{
+		g:       g,
+		old:     old,
+		new:     new,
+		oldPath: path.Join(importPath, old.pkg),
+		newPath: path.Join(importPath, new.pkg),
+		suffix:  strings.Title(old.pkg) + "To" + strings.Title(new.pkg),
+	}
+	for _, name := range old.names {
+		if _, ok := new.trees[name]; ok {
+			c.convertStruct(name)
+		}
+	}
+
+	var res bytes.Buffer
+	fmt.Fprintf(&res, "// Code generated by parsexsd; DO NOT EDIT.\n\npackage %s\n\n", g.pkg)
+	fmt.Fprintf(&res, "import (\n%q\n%q\n%q\n)\n\n", "github.com/rpoletaev/parsexsd/xsd", c.oldPath, c.newPath)
+	c.body.WriteTo(&res)
+
+	content, err := imports.Process("", res.Bytes(), &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return File{}, nil, fmt.Errorf("could not format converters: %v", err)
+	}
+	name := fmt.Sprintf("convert_%s_%s.go", old.pkg, new.pkg)
+	return File{Name: name, Package: g.pkg, Content: content}, c.report, nil
+}
+
+func (c *converter) convertStruct(name string) {
+	typ := c.g.structName(name)
+	fmt.Fprintf(&c.body, "// %s%s converts %s of %s to %s\n", typ, c.suffix, typ, c.old.pkg, c.new.pkg)
+	fmt.Fprintf(&c.body, "func %s%s(src %s.%s) (dst %s.%s) {\n", typ, c.suffix, c.old.pkg, typ, c.new.pkg, typ)
+
+	oldFields := c.old.fields(c.g, c.old.trees[name])
+	newFields := c.new.fields(c.g, c.new.trees[name])
+	oldByName := make(map[string]structField, len(oldFields))
+	for _, f := range oldFields {
+		oldByName[f.name] = f
+	}
+	newByName := make(map[string]structField, len(newFields))
+	for _, f := range newFields {
+		newByName[f.name] = f
+	}
+
+	for _, nf := range newFields {
+		of, ok := oldByName[nf.name]
+		switch {
+		case !ok:
+			c.todo(ConvertReport{Struct: typ, Field: nf.name, Kind: "added", New: nf.String()})
+			fmt.Fprintf(&c.body, "// TODO: %s is added in %s\n// dst.%s =\n", nf.name, c.new.pkg, nf.name)
+		case of.String() != nf.String() || (of.struc == "") != (nf.struc == ""):
+			c.todo(ConvertReport{Struct: typ, Field: nf.name, Kind: "changed", Old: of.String(), New: nf.String()})
+			fmt.Fprintf(&c.body, "// TODO: %s changed type from %s to %s\n// dst.%s = src.%s\n", nf.name, of, nf, nf.name, nf.name)
+		default:
+			c.convertField(nf)
+		}
+	}
+	for _, of := range oldFields {
+		if _, ok := newByName[of.name]; !ok {
+			c.todo(ConvertReport{Struct: typ, Field: of.name, Kind: "removed", Old: of.String()})
+			fmt.Fprintf(&c.body, "// TODO: %s is removed in %s\n// _ = src.%s\n", of.name, c.new.pkg, of.name)
+		}
+	}
+	fmt.Fprintf(&c.body, "return dst\n}\n\n")
+}
+
+// convertField copies the field of the same type, structs are converted
+// by their functions
+func (c *converter) convertField(f structField) {
+	if f.struc == "" {
+		fmt.Fprintf(&c.body, "dst.%s = src.%s\n", f.name, f.name)
+		return
+	}
+
+	value := func(v string) string {
+		typ := c.g.structName(f.struc)
+		if f.nillable {
+			return fmt.Sprintf("xsd.Nillable[%s.%s]{Value: %s%s(%s.Value), Nil: %s.Nil}", c.new.pkg, typ, typ, c.suffix, v, v)
+		}
+		return fmt.Sprintf("%s%s(%s)", typ, c.suffix, v)
+	}
+	if f.list {
+		fmt.Fprintf(&c.body, "for _, v := range src.%s {\ndst.%s = append(dst.%s, %s)\n}\n", f.name, f.name, f.name, value("v"))
+		return
+	}
+	fmt.Fprintf(&c.body, "dst.%s = %s\n", f.name, value("src."+f.name))
+}
+
+func (c *converter) todo(r ConvertReport) {
+	c.report = append(c.report, r)
+}
@@ -0,0 +1,113 @@
+package gen
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateConverters(t *testing.T) {
+	const types = `<xs:complexType name="lineType"><xs:sequence>
+		<xs:element name="code" type="xs:string"/>
+	</xs:sequence></xs:complexType>
+	<xs:complexType name="supplierType"><xs:sequence>
+		<xs:element name="name" type="xs:string"/>
+	</xs:sequence></xs:complexType>`
+	dir := t.TempDir()
+	old := writeVersion(t, filepath.Join(dir, "4.4"), "4.4", types+`<xs:element name="export"><xs:complexType><xs:sequence>
+		<xs:element name="id" type="xs:string"/>
+		<xs:element name="count" type="xs:int"/>
+		<xs:element name="line" type="lineType" maxOccurs="unbounded"/>
+		<xs:element name="supplier" type="supplierType" nillable="true"/>
+		<xs:element name="note" type="xs:string"/>
+	</xs:sequence></xs:complexType></xs:element>`)
+	new := writeVersion(t, filepath.Join(dir, "6.4"), "6.4", types+`<xs:element name="export"><xs:complexType><xs:sequence>
+		<xs:element name="id" type="xs:string"/>
+		<xs:element name="count" type="xs:string"/>
+		<xs:element name="line" type="lineType" maxOccurs="unbounded"/>
+		<xs:element name="supplier" type="supplierType" nillable="true"/>
+		<xs:element name="comment" type="xs:string"/>
+	</xs:sequence></xs:complexType></xs:element>`)
+
+	schemas, err := Load([]string{old, new}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, importPath := generatedDir(t)
+	opts := DefaultOptions()
+	opts.ImportPath = importPath
+	opts.Converters = true
+	files, err := GenerateVersions(schemas, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var code, report string
+	for _, f := range files {
+		switch f.Name {
+		case "convert_v4_4_v6_4.go":
+			code = string(f.Content)
+		case "convert_v4_4_v6_4.txt":
+			report = string(f.Content)
+		}
+	}
+	for _, want := range []string{
+		"dst.ID = src.ID\n",
+		"// TODO: Count changed type from int64 to string\n",
+		"// TODO: Comment is added in v6_4\n",
+		"// dst.Comment =\n",
+		"// TODO: Note is removed in v6_4\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("converters have no %q:\n%s", want, code)
+		}
+	}
+	for _, want := range []string{
+		"Export.Count changed type: int64 -> string",
+		"Export.Comment is added: string",
+		"Export.Note is removed: string",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report has no %q:\n%s", want, report)
+		}
+	}
+	if n := strings.Count(report, "\n") - 2; n != 3 {
+		t.Errorf("report has %d fields, want 3:\n%s", n, report)
+	}
+
+	main := `package main
+
+import (
+	"fmt"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+	"` + importPath + `/v4_4"
+)
+
+func main() {
+	src := v4_4.Export{
+		ID:       "1",
+		Count:    2,
+		Line:     []v4_4.LineType{{Code: "a"}, {Code: "b"}},
+		Supplier: xsd.Nillable[v4_4.SupplierType]{Value: v4_4.SupplierType{Name: "s"}},
+		Note:     "n",
+	}
+	fmt.Printf("%+v\n", ExportV4_4ToV6_4(src))
+	src.Supplier = xsd.Nillable[v4_4.SupplierType]{Nil: true}
+	fmt.Printf("%+v\n", ExportV4_4ToV6_4(src).Supplier)
+}
+`
+	got := strings.Split(strings.TrimSpace(runGenerated(t, out, files, main)), "\n")
+	want := []string{
+		"{ID:1 Count: Line:[{Code:a} {Code:b}] Supplier:{Value:{Name:s} Nil:false} Comment:}",
+		"{Value:{Name:} Nil:true}",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("output is %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("converted %s, want %s", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package gen
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+// Kinds of changes between versions of schemas
+const (
+	TypeAdded          = "type-added"
+	TypeRemoved        = "type-removed"
+	TypeRenamed        = "type-renamed"
+	ElementAdded       = "element-added"
+	ElementRemoved     = "element-removed"
+	ElementRenamed     = "element-renamed"
+	AttributeAdded     = "attribute-added"
+	AttributeRemoved   = "attribute-removed"
+	AttributeRenamed   = "attribute-renamed"
+	TypeChanged        = "type-changed"
+	OccurrenceChanged  = "occurrence-changed"
+	FacetsChanged      = "facets-changed"
+	EnumerationAdded   = "enumeration-added"
+	EnumerationRemoved = "enumeration-removed"
+)
+
+// Change is a difference between trees of elements of two versions of
+// schemas. Path is the name of the root element or the complex type followed
+// by names of child elements and @names of attributes, separated by '/'.
+// Root elements of target namespaces are prefixed like
+// integration:bankGuaranteeRefusal.
+type Change struct {
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+	// Breaking marks changes which make decoders generated from the old
+	// schemas fail or lose required data on documents of the new ones
+	Breaking bool `json:"breaking"`
+}
+
+// Diff returns changes between trees of the old and the new schemas built by
+// Build, ordered by path. Named types and child elements which are removed
+// while ones with the same content are added are reported as renamed.
+func Diff(old, new []*xsd.XmlTree) []Change {
+	d := differ{renamed: make(map[string]string)}
+	d.trees(old, new)
+	sort.SliceStable(d.changes, func(i, j int) bool {
+		return d.changes[i].Path < d.changes[j].Path
+	})
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+	// renamed maps old names of named types to new ones
+	renamed map[string]string
+}
+
+func (d *differ) add(c Change) {
+	d.changes = append(d.changes, c)
+}
+
+// trees compares root elements and named types
+func (d *differ) trees(old, new []*xsd.XmlTree) {
+	oldTrees, newTrees := treesByKey(old), treesByKey(new)
+
+	var removed, added []*xsd.XmlTree
+	for _, t := range old {
+		if _, ok := newTrees[treeKey(t)]; !ok {
+			removed = append(removed, t)
+		}
+	}
+	for _, t := range new {
+		if _, ok := oldTrees[treeKey(t)]; !ok {
+			added = append(added, t)
+		}
+	}
+
+	pairs, restRemoved, restAdded := pair(len(removed), len(added), func(i, j int) bool {
+		o, n := removed[i], added[j]
+		return !o.Root && !n.Root && signature(o) == signature(n)
+	})
+	for _, p := range pairs {
+		o, n := removed[p[0]], added[p[1]]
+		d.renamed[o.Name] = n.Name
+		d.add(Change{Kind: TypeRenamed, Path: o.Name, Old: o.Name, New: n.Name})
+	}
+	for _, i := range restRemoved {
+		kind := TypeRemoved
+		if removed[i].Root {
+			kind = ElementRemoved
+		}
+		d.add(Change{Kind: kind, Path: treePath(removed[i])})
+	}
+	for _, j := range restAdded {
+		kind := TypeAdded
+		if added[j].Root {
+			kind = ElementAdded
+		}
+		d.add(Change{Kind: kind, Path: treePath(added[j])})
+	}
+
+	for _, t := range old {
+		if n, ok := newTrees[treeKey(t)]; ok {
+			d.tree(treePath(t), t, n)
+		}
+	}
+}
+
+// tree compares an element or a type present in both versions
+func (d *differ) tree(path string, o, n *xsd.XmlTree) {
+	if ot, nt := d.oldTypeName(o), typeName(n); ot != nt {
+		d.add(Change{Kind: TypeChanged, Path: path, Old: ot, New: nt, Breaking: true})
+	}
+	if oo, no := occurrence(o), occurrence(n); oo != no {
+		breaking := (!o.List && n.List) || (!o.Optional && n.Optional) || (!o.Nillable && n.Nillable)
+		d.add(Change{Kind: OccurrenceChanged, Path: path, Old: oo, New: no, Breaking: breaking})
+	}
+	d.facets(path, o.Facets, n.Facets)
+
+	if o.StructNeeded && n.StructNeeded {
+		d.attributes(path, o.Attribs, n.Attribs)
+		d.children(path, o.Children, n.Children)
+	}
+}
+
+func (d *differ) children(path string, old, new []*xsd.XmlTree) {
+	oldChildren, newChildren := childrenByName(old), childrenByName(new)
+
+	var removed, added []*xsd.XmlTree
+	for _, c := range old {
+		if _, ok := newChildren[c.Name]; !ok {
+			removed = append(removed, c)
+		}
+	}
+	for _, c := range new {
+		if _, ok := oldChildren[c.Name]; !ok {
+			added = append(added, c)
+		}
+	}
+
+	// optional elements are not paired, removing one and adding another
+	// is not breaking
+	pairs, restRemoved, restAdded := pair(len(removed), len(added), func(i, j int) bool {
+		o, n := removed[i], added[j]
+		return !o.Optional && d.oldTypeName(o) == typeName(n) && occurrence(o) == occurrence(n)
+	})
+	for _, p := range pairs {
+		o, n := removed[p[0]], added[p[1]]
+		d.add(Change{Kind: ElementRenamed, Path: path + "/" + o.Name, Old: o.Name, New: n.Name, Breaking: true})
+	}
+	for _, i := range restRemoved {
+		c := removed[i]
+		d.add(Change{Kind: ElementRemoved, Path: path + "/" + c.Name, Old: describe(c), Breaking: !c.Optional})
+	}
+	for _, j := range restAdded {
+		c := added[j]
+		d.add(Change{Kind: ElementAdded, Path: path + "/" + c.Name, New: describe(c), Breaking: !c.Optional})
+	}
+
+	for _, c := range old {
+		if n, ok := newChildren[c.Name]; ok {
+			d.tree(path+"/"+c.Name, c, n)
+		}
+	}
+}
+
+func (d *differ) attributes(path string, old, new []xsd.XmlAttrib) {
+	oldAttrs, newAttrs := attribsByName(old), attribsByName(new)
+
+	var removed, added []xsd.XmlAttrib
+	for _, a := range old {
+		if _, ok := newAttrs[a.Name]; !ok {
+			removed = append(removed, a)
+		}
+	}
+	for _, a := range new {
+		if _, ok := oldAttrs[a.Name]; !ok {
+			added = append(added, a)
+		}
+	}
+
+	pairs, restRemoved, restAdded := pair(len(removed), len(added), func(i, j int) bool {
+		o, n := removed[i], added[j]
+		return !o.Optional && !n.Optional && o.Type == n.Type
+	})
+	for _, p := range pairs {
+		o, n := removed[p[0]], added[p[1]]
+		d.add(Change{Kind: AttributeRenamed, Path: path + "/@" + o.Name, Old: o.Name, New: n.Name, Breaking: true})
+	}
+	for _, i := range restRemoved {
+		a := removed[i]
+		d.add(Change{Kind: AttributeRemoved, Path: path + "/@" + a.Name, Old: a.Type, Breaking: !a.Optional})
+	}
+	for _, j := range restAdded {
+		a := added[j]
+		d.add(Change{Kind: AttributeAdded, Path: path + "/@" + a.Name, New: a.Type, Breaking: !a.Optional})
+	}
+
+	for _, o := range old {
+		n, ok := newAttrs[o.Name]
+		if !ok {
+			continue
+		}
+		attrPath := path + "/@" + o.Name
+		if o.Type != n.Type {
+			d.add(Change{Kind: TypeChanged, Path: attrPath, Old: o.Type, New: n.Type, Breaking: true})
+		}
+		if o.Optional != n.Optional {
+			d.add(Change{Kind: OccurrenceChanged, Path: attrPath, Old: attribOccurrence(o), New: attribOccurrence(n), Breaking: n.Optional})
+		}
+		d.facets(attrPath, o.Facets, n.Facets)
+	}
+}
+
+// facets compares facets of values, enumeration values are reported one by
+// one
+func (d *differ) facets(path string, o, n *xsd.Facets) {
+	var of, nf xsd.Facets
+	if o != nil {
+		of = *o
+	}
+	if n != nil {
+		nf = *n
+	}
+	oldValues, newValues := of.Enumeration, nf.Enumeration
+	of.Enumeration, nf.Enumeration = nil, nil
+
+	if of.String() != nf.String() {
+		d.add(Change{Kind: FacetsChanged, Path: path, Old: of.String(), New: nf.String(), Breaking: loosened(of, nf)})
+	}
+
+	oldSet, newSet := stringSet(oldValues), stringSet(newValues)
+	for _, v := range newValues {
+		if !oldSet[v] {
+			d.add(Change{Kind: EnumerationAdded, Path: path, New: v, Breaking: len(oldValues) > 0})
+		}
+	}
+	for _, v := range oldValues {
+		if !newSet[v] {
+			d.add(Change{Kind: EnumerationRemoved, Path: path, Old: v, Breaking: len(newValues) == 0})
+		}
+	}
+}
+
+// oldTypeName returns type name of the old element with renamed types
+// replaced by their new names
+func (d *differ) oldTypeName(t *xsd.XmlTree) string {
+	if name, ok := d.renamed[t.Type]; ok && !t.StructNeeded {
+		return name
+	}
+	return typeName(t)
+}
+
+// typeName returns type of the element as decoders see it, complex types
+// declared inline are structs
+func typeName(t *xsd.XmlTree) string {
+	switch {
+	case t.Cdata:
+		return "text " + t.Type
+	case t.StructNeeded:
+		return "struct"
+	}
+	return t.Type
+}
+
+// occurrence returns occurrence of the element like 0..1 or 1..n
+func occurrence(t *xsd.XmlTree) string {
+	res := "1"
+	if t.Optional {
+		res = "0"
+	}
+	if t.List {
+		res += "..n"
+	} else if t.Optional {
+		res += "..1"
+	}
+	if t.Nillable {
+		res += " nillable"
+	}
+	return res
+}
+
+func attribOccurrence(a xsd.XmlAttrib) string {
+	if a.Optional {
+		return "optional"
+	}
+	return "required"
+}
+
+// describe returns type and occurrence of the element
+func describe(t *xsd.XmlTree) string {
+	return typeName(t) + " " + occurrence(t)
+}
+
+// loosened reports whether the new facets accept values the old ones do
+// not, which makes validation of the old decoders fail
+func loosened(o, n xsd.Facets) bool {
+	switch {
+	case o.WhiteSpace != n.WhiteSpace:
+		return true
+	case o.Length > 0 && n.Length != o.Length:
+		return true
+	case o.MaxLength > 0 && (n.MaxLength == 0 || n.MaxLength > o.MaxLength):
+		return true
+	case n.MinLength < o.MinLength:
+		return true
+	}
+
+	if len(o.Patterns) > 0 {
+		if len(o.Patterns) != len(n.Patterns) {
+			return true
+		}
+		for i := range o.Patterns {
+			if o.Patterns[i] != n.Patterns[i] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// signature returns content of the named type without its name, types with
+// the same signature are the same. Locations of schemas are left out, as
+// they differ between releases.
+func signature(t *xsd.XmlTree) string {
+	c := withoutLocations(t)
+	c.Name, c.Namespace = "", ""
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// withoutLocations returns copy of the tree with locations of schemas
+// cleared
+func withoutLocations(t *xsd.XmlTree) *xsd.XmlTree {
+	c := *t
+	c.Location = ""
+	c.Children = make([]*xsd.XmlTree, len(t.Children))
+	for i, child := range t.Children {
+		c.Children[i] = withoutLocations(child)
+	}
+	return &c
+}
+
+// pair matches removed and added items by their indexes, and returns
+// matched pairs and indexes of items left unmatched. Items are matched only
+// if neither of them has another candidate, ambiguous ones are reported as
+// removed and added.
+func pair(removed, added int, same func(i, j int) bool) ([][2]int, []int, []int) {
+	removedCandidates := make([]int, removed)
+	addedCandidates := make([]int, added)
+	for i := 0; i < removed; i++ {
+		for j := 0; j < added; j++ {
+			if same(i, j) {
+				removedCandidates[i]++
+				addedCandidates[j]++
+			}
+		}
+	}
+
+	var pairs [][2]int
+	matched := make([]bool, added)
+	var restRemoved, restAdded []int
+	for i := 0; i < removed; i++ {
+		found := false
+		for j := 0; j < added && !found && removedCandidates[i] == 1; j++ {
+			if addedCandidates[j] == 1 && same(i, j) {
+				pairs = append(pairs, [2]int{i, j})
+				matched[j], found = true, true
+			}
+		}
+		if !found {
+			restRemoved = append(restRemoved, i)
+		}
+	}
+	for j, ok := range matched {
+		if !ok {
+			restAdded = append(restAdded, j)
+		}
+	}
+	return pairs, restRemoved, restAdded
+}
+
+// treeKey identifies root elements by namespaces and names, and named types
+// by names, which are unique
+func treeKey(t *xsd.XmlTree) string {
+	if t.Root {
+		return "element {" + t.Namespace + "}" + t.Name
+	}
+	return "type " + t.Name
+}
+
+func treePath(t *xsd.XmlTree) string {
+	if t.Root && t.Namespace != "" {
+		return namespacePackage(t.Namespace, t.Namespace) + ":" + t.Name
+	}
+	return t.Name
+}
+
+func treesByKey(trees []*xsd.XmlTree) map[string]*xsd.XmlTree {
+	res := make(map[string]*xsd.XmlTree, len(trees))
+	for _, t := range trees {
+		if _, ok := res[treeKey(t)]; !ok {
+			res[treeKey(t)] = t
+		}
+	}
+	return res
+}
+
+func childrenByName(children []*xsd.XmlTree) map[string]*xsd.XmlTree {
+	res := make(map[string]*xsd.XmlTree, len(children))
+	for _, c := range children {
+		if _, ok := res[c.Name]; !ok {
+			res[c.Name] = c
+		}
+	}
+	return res
+}
+
+func attribsByName(attrs []xsd.XmlAttrib) map[string]xsd.XmlAttrib {
+	res := make(map[string]xsd.XmlAttrib, len(attrs))
+	for _, a := range attrs {
+		if _, ok := res[a.Name]; !ok {
+			res[a.Name] = a
+		}
+	}
+	return res
+}
+
+func stringSet(values []string) map[string]bool {
+	res := make(map[string]bool, len(values))
+	for _, v := range values {
+		res[v] = true
+	}
+	return res
+}
@@ -0,0 +1,209 @@
+package gen
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+// buildSchema returns trees of the schema with the content
+func buildSchema(t *testing.T, content string) []*xsd.XmlTree {
+	var s xsd.Schema
+	src := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + content + `</xs:schema>`
+	if err := xml.Unmarshal([]byte(src), &s); err != nil {
+		t.Fatal(err)
+	}
+	return Build([]xsd.Schema{s}, BuildOptions{Warn: func(xsd.Warning) {}})
+}
+
+func TestDiff(t *testing.T) {
+	const order = `<xs:element name="order"><xs:complexType><xs:sequence>
+		<xs:element name="id" type="xs:string"/>
+		<xs:element name="note" type="xs:string" minOccurs="0"/>
+	</xs:sequence><xs:attribute name="kind" type="xs:string"/></xs:complexType></xs:element>`
+	const item = `<xs:complexType name="item"><xs:sequence>
+		<xs:element name="code" type="xs:string"/>
+	</xs:sequence></xs:complexType>`
+
+	tests := []struct {
+		name     string
+		old, new string
+		want     []Change
+	}{
+		{
+			name: "same",
+			old:  order + item,
+			new:  order + item,
+		},
+		{
+			name: "added",
+			old:  order,
+			new: item + `<xs:element name="order"><xs:complexType><xs:sequence>
+				<xs:element name="id" type="xs:string"/>
+				<xs:element name="note" type="xs:string" minOccurs="0"/>
+				<xs:element name="total" type="xs:decimal"/>
+				<xs:element name="comment" type="xs:string" minOccurs="0"/>
+			</xs:sequence><xs:attribute name="kind" type="xs:string"/>
+			<xs:attribute name="date" type="xs:date" use="required"/></xs:complexType></xs:element>`,
+			want: []Change{
+				{Kind: TypeAdded, Path: "item"},
+				{Kind: AttributeAdded, Path: "order/@date", New: "xsd.Date", Breaking: true},
+				{Kind: ElementAdded, Path: "order/comment", New: "string 0..1"},
+				{Kind: ElementAdded, Path: "order/total", New: "float64 1", Breaking: true},
+			},
+		},
+		{
+			name: "removed",
+			old:  order + item,
+			new: `<xs:element name="order"><xs:complexType><xs:sequence>
+				<xs:element name="id" type="xs:string"/>
+			</xs:sequence></xs:complexType></xs:element>`,
+			want: []Change{
+				{Kind: TypeRemoved, Path: "item"},
+				{Kind: AttributeRemoved, Path: "order/@kind", Old: "string"},
+				{Kind: ElementRemoved, Path: "order/note", Old: "string 0..1"},
+			},
+		},
+		{
+			name: "renamed",
+			old: order + item + `<xs:element name="items"><xs:complexType><xs:sequence>
+				<xs:element name="item" type="item" maxOccurs="unbounded"/>
+			</xs:sequence></xs:complexType></xs:element>`,
+			new: `<xs:element name="order"><xs:complexType><xs:sequence>
+				<xs:element name="number" type="xs:string"/>
+				<xs:element name="note" type="xs:string" minOccurs="0"/>
+			</xs:sequence><xs:attribute name="type" type="xs:string"/></xs:complexType></xs:element>
+			<xs:complexType name="position"><xs:sequence>
+				<xs:element name="code" type="xs:string"/>
+			</xs:sequence></xs:complexType>
+			<xs:element name="items"><xs:complexType><xs:sequence>
+				<xs:element name="item" type="position" maxOccurs="unbounded"/>
+			</xs:sequence></xs:complexType></xs:element>`,
+			want: []Change{
+				{Kind: TypeRenamed, Path: "item", Old: "item", New: "position"},
+				{Kind: AttributeRemoved, Path: "order/@kind", Old: "string"},
+				{Kind: AttributeAdded, Path: "order/@type", New: "string"},
+				{Kind: ElementRenamed, Path: "order/id", Old: "id", New: "number", Breaking: true},
+			},
+		},
+		{
+			name: "optional elements are not renamed",
+			old:  order,
+			new: `<xs:element name="order"><xs:complexType><xs:sequence>
+				<xs:element name="id" type="xs:string"/>
+				<xs:element name="remark" type="xs:string" minOccurs="0"/>
+			</xs:sequence><xs:attribute name="kind" type="xs:string"/></xs:complexType></xs:element>`,
+			want: []Change{
+				{Kind: ElementRemoved, Path: "order/note", Old: "string 0..1"},
+				{Kind: ElementAdded, Path: "order/remark", New: "string 0..1"},
+			},
+		},
+		{
+			name: "ambiguous renames",
+			old: `<xs:complexType name="pair"><xs:sequence>
+				<xs:element name="a" type="xs:string"/>
+				<xs:element name="b" type="xs:string"/>
+			</xs:sequence><xs:attribute name="x" type="xs:int" use="required"/></xs:complexType>`,
+			new: `<xs:complexType name="pair"><xs:sequence>
+				<xs:element name="c" type="xs:string"/>
+				<xs:element name="d" type="xs:string"/>
+			</xs:sequence><xs:attribute name="y" type="xs:int" use="required"/></xs:complexType>`,
+			want: []Change{
+				{Kind: AttributeRenamed, Path: "pair/@x", Old: "x", New: "y", Breaking: true},
+				{Kind: ElementRemoved, Path: "pair/a", Old: "string 1", Breaking: true},
+				{Kind: ElementRemoved, Path: "pair/b", Old: "string 1", Breaking: true},
+				{Kind: ElementAdded, Path: "pair/c", New: "string 1", Breaking: true},
+				{Kind: ElementAdded, Path: "pair/d", New: "string 1", Breaking: true},
+			},
+		},
+		{
+			name: "type changed",
+			old:  item,
+			new: `<xs:complexType name="item"><xs:sequence>
+				<xs:element name="code" type="xs:int"/>
+			</xs:sequence></xs:complexType>`,
+			want: []Change{
+				{Kind: TypeChanged, Path: "item/code", Old: "string", New: "int64", Breaking: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got := Diff(buildSchema(t, tt.old), buildSchema(t, tt.new))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Diff() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// buildRelease writes the schemas into the directory and returns trees of
+// all of them loaded from the input, which is the directory or a schema of
+// it
+func buildRelease(t *testing.T, dir, input string, files map[string]string) []*xsd.XmlTree {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		src := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns="urn:shop" targetNamespace="urn:shop">` + content + `</xs:schema>`
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schemas, err := Load([]string{filepath.Join(dir, input)}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []xsd.Schema
+	seen := make(map[string]bool)
+	for _, s := range schemas {
+		for _, schema := range s.Schemas {
+			if !seen[schema.Location] {
+				seen[schema.Location] = true
+				all = append(all, schema)
+			}
+		}
+	}
+	return Build(all, BuildOptions{Warn: func(xsd.Warning) {}})
+}
+
+// release returns schemas of a release with the item type named name,
+// which is defined in the types schema
+func release(types, name string) map[string]string {
+	return map[string]string{
+		types: `<xs:complexType name="code"><xs:sequence>
+			<xs:element name="value" type="xs:string"/>
+		</xs:sequence></xs:complexType>
+		<xs:complexType name="` + name + `"><xs:sequence>
+			<xs:element name="code" type="code"/>
+			<xs:element name="count" type="xs:int"/>
+		</xs:sequence></xs:complexType>`,
+		"order.xsd": `<xs:include schemaLocation="` + types + `"/>
+		<xs:element name="order"><xs:complexType><xs:sequence>
+			<xs:element name="item" type="` + name + `" maxOccurs="unbounded"/>
+		</xs:sequence></xs:complexType></xs:element>`,
+	}
+}
+
+func TestDiffReleases(t *testing.T) {
+	tests := []struct {
+		name               string
+		oldTypes, newTypes string
+		input              string
+	}{
+		{"directories", "types-1.0.xsd", "types-1.1.xsd", "."},
+		{"entry points", "types.xsd", "types.xsd", "order.xsd"},
+	}
+	want := []Change{{Kind: TypeRenamed, Path: "item", Old: "item", New: "position"}}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		old := buildRelease(t, filepath.Join(dir, "old"), tt.input, release(tt.oldTypes, "item"))
+		new := buildRelease(t, filepath.Join(dir, "new"), tt.input, release(tt.newTypes, "position"))
+		if got := Diff(old, new); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Diff() = %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+// Package gen generates XML decoding/encoding Go structs from XSD schemas.
+// Schemas are read by Load, turned into trees of elements by Build and into
+// Go code by Generate.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+// BuildOptions are options of building trees of elements from schemas
+type BuildOptions struct {
+	// StreamBinary decodes base64Binary elements into temp files
+	StreamBinary bool
+	// Nillable wraps nillable elements into xsd.Nillable
+	Nillable bool
+	// Types maps XSD type names, qualified like {namespace}name or local, to
+	// Go types. Types of other packages are given with import path, e.g.
+	// github.com/google/uuid.UUID
+	Types map[string]string
+	// Warn receives constructs of schemas which are not supported, they are
+	// logged as warnings if it is nil
+	Warn func(xsd.Warning)
+}
+
+// Layouts of generated files
+const (
+	// LayoutPackage generates a file per package, schemas go to packages by
+	// target namespaces of their entry points
+	LayoutPackage = ""
+	// LayoutSchema generates a file per schema defining the types
+	LayoutSchema = "schema"
+	// LayoutElement generates a file per top-level element and complex
+	// type, named like export_gen.go
+	LayoutElement = "element"
+	// LayoutNamespace generates a package per target namespace of the
+	// schemas defining the types, packages import each other
+	LayoutNamespace = "namespace"
+)
+
+// Options are options of code generation
+type Options struct {
+	BuildOptions
+
+	// Package is the Go package of generated code
+	Package string
+	// Namespaces maps target namespaces of schemas to Go packages
+	Namespaces map[string]string
+	// Layout is the way types are split into files and packages
+	Layout string
+	// ImportPath is the import path of the output directory, packages of
+	// LayoutNamespace are imported from its subdirectories
+	ImportPath string
+	// Prefix of generated struct names
+	Prefix string
+	// Exported makes generated structs exported
+	Exported bool
+	// Validate generates Validate methods
+	Validate bool
+	// Initialisms are additional spellings of name parts, e.g. Inn: INN
+	Initialisms map[string]string
+	// VersionAttribute is the attribute of documents holding their schema
+	// version, it is read by the dispatcher of GenerateVersions
+	VersionAttribute string
+	// Converters generates functions converting structs of every version
+	// of GenerateVersions to the next one
+	Converters bool
+	// Registry generates the xsd.TypeRegistry variable of root elements
+	// and the version of the schemas into the first file of every package,
+	// plugins export it for xsd.OpenPlugin
+	Registry bool
+}
+
+// DefaultOptions returns options used by the command line tool by default
+func DefaultOptions() Options {
+	return Options{
+		BuildOptions: BuildOptions{Nillable: true},
+		Package:      "main",
+		Exported:     true,
+		Validate:     true,
+
+		VersionAttribute: "schemeVersion",
+	}
+}
+
+// File is a generated Go file
+type File struct {
+	// Name is the path of the file relative to the output directory. Code
+	// of a single package is named after its first schema, every package
+	// of several goes to the directory named after it. Files of schema and
+	// element layouts are named after the schema or the element.
+	Name    string
+	Package string
+	Content []byte
+	// Types is the number of structs generated into the file
+	Types int
+}
+
+// Build returns trees of root elements and complex types of the schemas
+func Build(schemas []xsd.Schema, opts BuildOptions) []*xsd.XmlTree {
+	bldr := xsd.NewBuilder(schemas)
+	bldr.StreamBinary(opts.StreamBinary)
+	bldr.Nillable(opts.Nillable)
+	bldr.OverrideTypes(opts.Types)
+	bldr.OnWarning(opts.Warn)
+	if opts.Warn == nil {
+		bldr.OnWarning(logWarning)
+	}
+	return bldr.BuildXML()
+}
+
+// logWarning logs the construct of the schema which is not supported
+func logWarning(w xsd.Warning) {
+	log.WithFields(log.Fields{
+		"schema":    w.File(),
+		"line":      w.Line,
+		"component": w.Component,
+	}).Warn(w.Message)
+}
+
+// Generate returns code of the schemas split into files and packages by
+// the layout of options
+func Generate(schemas []Schema, opts Options) ([]File, error) {
+	g := generator{
+		prefix:      opts.Prefix,
+		exported:    opts.Exported,
+		validate:    opts.Validate,
+		initialisms: newInitialisms(opts.Initialisms),
+	}
+
+	switch opts.Layout {
+	case LayoutPackage, LayoutSchema, LayoutElement:
+	case LayoutNamespace:
+		return generateNamespaces(g, schemas, opts)
+	default:
+		return nil, fmt.Errorf("unknown layout %q", opts.Layout)
+	}
+
+	// schemas go to packages by target namespaces of their entry points
+	var pkgs []string
+	groups := make(map[string][]Schema)
+	for _, s := range schemas {
+		pkg := opts.packageOf(s.TargetNamespace(), opts.Package)
+		if _, ok := groups[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		groups[pkg] = append(groups[pkg], s)
+	}
+
+	var files []File
+	for _, pkg := range pkgs {
+		var all []xsd.Schema
+		for _, s := range groups[pkg] {
+			all = append(all, s.Schemas...)
+		}
+
+		dir := ""
+		if len(pkgs) > 1 {
+			dir = pkg
+		}
+		base := fileName(groups[pkg][0].Location)
+		if len(pkgs) > 1 {
+			base = pkg + ".go"
+		}
+
+		g.pkg = pkg
+		g.version = schemasVersion(groups[pkg])
+		pkgFiles, err := g.files(Build(all, opts.BuildOptions), opts.Layout, dir, base, opts.Registry)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, pkgFiles...)
+	}
+	return files, nil
+}
+
+// generateNamespaces generates a package for every target namespace of the
+// schemas defining the types
+func generateNamespaces(g generator, schemas []Schema, opts Options) ([]File, error) {
+	var all []xsd.Schema
+	for _, s := range schemas {
+		all = append(all, s.Schemas...)
+	}
+	trees := Build(all, opts.BuildOptions)
+	g.version = schemasVersion(schemas)
+
+	var pkgs []string
+	groups := make(map[string][]*xsd.XmlTree)
+	typePackages := make(map[string]string)
+	for _, t := range trees {
+		pkg := opts.packageOf(t.Namespace, namespacePackage(t.Namespace, opts.Package))
+		if _, ok := groups[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		groups[pkg] = append(groups[pkg], t)
+		if !t.Root {
+			typePackages[t.Name] = pkg
+		}
+	}
+	if len(pkgs) > 1 && opts.ImportPath == "" {
+		return nil, fmt.Errorf("types are generated into %d packages, import path of the output is needed", len(pkgs))
+	}
+	if len(pkgs) > 1 && !opts.Exported {
+		return nil, fmt.Errorf("types are generated into %d packages, they must be exported", len(pkgs))
+	}
+
+	var files []File
+	for _, pkg := range pkgs {
+		current := pkg
+		g.pkg = pkg
+		g.foreign = func(e *xsd.XmlTree) (string, string) {
+			other, ok := typePackages[e.Type]
+			if !ok || e.StructNeeded || e.Cdata || other == current {
+				return "", ""
+			}
+			return path.Join(opts.ImportPath, other), other
+		}
+
+		g.registry = nil
+		if opts.Registry {
+			g.registry = groups[pkg]
+		}
+
+		var buf bytes.Buffer
+		g.types = make(map[string]struct{})
+		if err := g.write(&buf, groups[pkg]); err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Join(pkg, pkg+".go"), Package: pkg, Content: buf.Bytes(), Types: len(g.types)})
+	}
+	return files, nil
+}
+
+// files generates files of the package by the layout, files are named after
+// the schema or the element in the directory, or base for the package
+// layout. The registry of the trees goes to the first file.
+func (g generator) files(trees []*xsd.XmlTree, layout, dir, base string, registry bool) ([]File, error) {
+	var names []string
+	groups := make(map[string][]*xsd.XmlTree)
+	elements := make(elementFiles)
+	for _, t := range trees {
+		name := base
+		switch layout {
+		case LayoutSchema:
+			if t.Location != "" {
+				name = fileName(t.Location)
+			}
+		case LayoutElement:
+			name = elements.name(t.Name)
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], t)
+	}
+
+	g.types = make(map[string]struct{})
+	var files []File
+	for i, name := range names {
+		g.registry = nil
+		if registry && i == 0 {
+			g.registry = trees
+		}
+
+		var buf bytes.Buffer
+		generated := len(g.types)
+		if err := g.write(&buf, groups[name]); err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: filepath.Join(dir, name), Package: g.pkg, Content: buf.Bytes(), Types: len(g.types) - generated})
+	}
+	return files, nil
+}
+
+// packageOf returns Go package of types of the namespace
+func (opts Options) packageOf(namespace, def string) string {
+	if pkg, ok := opts.Namespaces[namespace]; ok {
+		return pkg
+	}
+	return def
+}
+
+// namespacePackage returns package name derived from the namespace, like
+// types for http://zakupki.gov.ru/oos/types/1, or def for no namespace
+func namespacePackage(namespace, def string) string {
+	var name string
+	for _, part := range strings.FieldsFunc(namespace, func(r rune) bool { return r == '/' || r == ':' }) {
+		if strings.Trim(part, "0123456789.v") != "" {
+			name = part
+		}
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return -1
+	}, name)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return def
+	}
+	return name
+}
+
+// fileName returns name of the Go file generated from the schema location
+func fileName(location string) string {
+	base := path.Base(filepath.ToSlash(location))
+	if u, err := url.PathUnescape(base); err == nil {
+		base = u
+	}
+	return strings.TrimSuffix(base, path.Ext(base)) + ".go"
+}
+
+// elementFiles maps files of the element layout to names of elements and
+// complex types they are generated for
+type elementFiles map[string]string
+
+// name returns the file of the element or complex type. Names which differ
+// only in case get numbered files, as they would be the same file on case
+// insensitive file systems.
+func (files elementFiles) name(element string) string {
+	base := elementFileBase(element)
+	name := base + "_gen.go"
+	for i := 2; files[name] != "" && files[name] != element; i++ {
+		name = fmt.Sprintf("%s_%d_gen.go", base, i)
+	}
+	if files[name] == "" && name != base+"_gen.go" {
+		log.Warnf("Files of %s and %s differ only in case, %s is generated into %s", files[base+"_gen.go"], element, element, name)
+	}
+	files[name] = element
+	return name
+}
+
+// elementFileBase returns the lower case name of the element without
+// characters other than letters, digits and underscores. The _gen suffix
+// added to it keeps names like windows or foo_test from being build
+// constraints, names starting with underscore are ignored by go build, so
+// it is trimmed.
+func elementFileBase(element string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, element)
+	if name = strings.TrimLeft(name, "_"); name == "" {
+		name = "type"
+	}
+	return name
+}
+
+// newInitialisms returns the replacer of the common initialisms and the
+// additional ones, which go first so they win over the common ones
+func newInitialisms(initialisms map[string]string) *strings.Replacer {
+	return strings.NewReplacer(append(initialismPairsOf(initialisms), initialismPairs...)...)
+}
+
+// initialismPairsOf returns old, new pairs of additional initialisms for
+// strings.Replacer, longer names go first so they win over their parts
+func initialismPairsOf(initialisms map[string]string) []string {
+	names := make([]string, 0, len(initialisms))
+	for name := range initialisms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	pairs := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		pairs = append(pairs, name, initialisms[name])
+	}
+	return pairs
+}
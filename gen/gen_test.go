@@ -0,0 +1,27 @@
+package gen
+
+import "testing"
+
+func TestElementFiles(t *testing.T) {
+	tests := []struct {
+		element string
+		file    string
+	}{
+		{"export", "export_gen.go"},
+		{"Export", "export_2_gen.go"},
+		{"export", "export_gen.go"},
+		{"EXPORT", "export_3_gen.go"},
+		{"windows", "windows_gen.go"},
+		{"foo_test", "foo_test_gen.go"},
+		{"_hidden", "hidden_gen.go"},
+		{"a.b-c", "a_b_c_gen.go"},
+		{"контракт", "контракт_gen.go"},
+		{"_", "type_gen.go"},
+	}
+	files := make(elementFiles)
+	for _, tt := range tests {
+		if file := files.name(tt.element); file != tt.file {
+			t.Errorf("file of %s = %s, want %s", tt.element, file, tt.file)
+		}
+	}
+}
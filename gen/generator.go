@@ -0,0 +1,566 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/rpoletaev/parsexsd/xsd"
+	"golang.org/x/tools/imports"
+)
+
+var (
+	// Struct field generated from an element attribute
+	attr = `{{ define "Attr" }}{{ printf "  %s " (lintTitle .Name) }}{{ printf "%s ` + "`xml:\\\"%s,attr\\\" json:\\\",omitempty\\\"`" + `" (typeName .Type) .Name }}
+{{ end }}`
+
+	// Struct field generated from an element child element
+	child = `{{ define "Child" }}{{ printf "  %s " (lintTitle .Name) }}{{ if .List }}[]{{ end }}{{ printf "%s ` + "`xml:\\\"%s,omitempty\\\" json:\\\",omitempty\\\"`" + `" (childType .) .Name }}
+{{ end }}`
+
+	// Struct field generated from the character data of an element
+	cdata = `{{ define "Cdata" }}{{ printf "%s %s ` + "`xml:\\\",chardata\\\" json:\\\",omitempty\\\"`" + `" (lintTitle .Name) (typeName .Type) }}
+{{ end }}`
+
+	// Validate method checking struct fields against the schema facets
+	validate = `{{ define "Validate" }}{{ printf "// Validate checks %s against facets of the schema\nfunc (v *%s) Validate() error {\n" (typeName .Name) (typeName .Name) }}{{ range $c := checks . }}{{ if $c.List }}for i := range v.{{ $c.Field }} {
+{{ end }}{{ if $c.Cond }}if {{ $c.Cond }} {
+{{ end }}if err := {{ if $c.Constraints }}xsd.CheckIdentityConstraints({{ $c.Expr }}, {{ constraints $c.Constraints }}){{ else if $c.Facets }}({{ facets $c.Facets }}).Check({{ $c.Value }}){{ else }}{{ $c.Expr }}.Validate(){{ end }}; err != nil {
+return xsd.NewFieldError("{{ $c.Name }}", err)
+}
+{{ if $c.Cond }}}
+{{ end }}{{ if $c.List }}}
+{{ end }}{{ end }}return nil
+}
+{{ end }}`
+
+	// Struct generated from a non-trivial element (with children and/or attributes)
+	elem = `{{ printf "// %s is generated from the XSD %s\ntype %s struct {\n" (typeName .Name) (component .) (typeName .Name) }}{{ range $a := .Attribs }}{{ template "Attr" $a }}{{ end }}{{ range $c := .Children }}{{ template "Child" $c }}{{ end }} {{ if .Cdata }}{{ template "Cdata" . }}{{ end }} }
+{{ if validate }}{{ template "Validate" . }}{{ end }}
+`
+)
+
+var (
+	// The initialism pairs are based on the commonInitialisms found in golang/lint
+	// https://github.com/golang/lint/blob/4946cea8b6efd778dc31dc2dbeb919535e1b7529/lint.go#L698-L738
+	//
+	initialismPairs = []string{
+		"Api", "API",
+		"Ascii", "ASCII",
+		"Cpu", "CPU",
+		"Css", "CSS",
+		"Dns", "DNS",
+		"Eof", "EOF",
+		"Guid", "GUID",
+		"Html", "HTML",
+		"Https", "HTTPS",
+		"Http", "HTTP",
+		"Id", "ID",
+		"Ip", "IP",
+		"Json", "JSON",
+		"Lhs", "LHS",
+		"Qps", "QPS",
+		"Ram", "RAM",
+		"Rhs", "RHS",
+		"Rpc", "RPC",
+		"Sla", "SLA",
+		"Smtp", "SMTP",
+		"Sql", "SQL",
+		"Ssh", "SSH",
+		"Tcp", "TCP",
+		"Tls", "TLS",
+		"Ttl", "TTL",
+		"Udp", "UDP",
+		"Uid", "UID",
+		"Ui", "UI",
+		"Uuid", "UUID",
+		"Uri", "URI",
+		"Url", "URL",
+		"Utf8", "UTF8",
+		"Vm", "VM",
+		"Xml", "XML",
+		"Xsrf", "XSRF",
+		"Xss", "XSS",
+	}
+
+	builtinTypes = [20]string{"bool", "byte", "complex128", "complex64", "error", "float32", "float64", "int", "int16", "int32", "int64", "int8", "rune", "string", "uint", "uint16", "uint32", "uint64", "uint8", "uintptr"}
+)
+
+// Generator is responsible for generating Go structs based on a given XML
+// schema tree.
+type generator struct {
+	pkg      string
+	prefix   string
+	exported bool
+	validate bool
+	// initialisms replace name parts in names of structs and fields, they
+	// are kept by the generator, so generations do not share them
+	initialisms *strings.Replacer
+	types       map[string]struct{}
+	// foreign returns import path and name of the package of the child
+	// type if it is generated into another package, nil means a single
+	// package
+	foreign func(e *xsd.XmlTree) (string, string)
+	// registry are roots of the package, the xsd.TypeRegistry of them is
+	// generated into the file if it is set
+	registry []*xsd.XmlTree
+	version  xsd.Version
+}
+
+// write generates a file of the package, types already generated into other
+// files of the package are skipped
+func (g generator) write(out io.Writer, roots []*xsd.XmlTree) error {
+	imps, err := resolveImports(roots)
+	if err != nil {
+		return err
+	}
+
+	tt, err := g.prepareTemplates(imps)
+	if err != nil {
+		return fmt.Errorf("could not prepare templates: %s", err)
+	}
+
+	// types are generated first to collect imports of overridden types
+	var body bytes.Buffer
+	for _, e := range roots {
+		if err := g.execute(e, tt, &body); err != nil {
+			return fmt.Errorf("could not generate %s: %v", component(e), err)
+		}
+	}
+	if g.registry != nil {
+		g.writeRegistry(&body, g.registry)
+	}
+
+	var res bytes.Buffer
+
+	if g.pkg != "" {
+		fmt.Fprintf(&res, "// Code generated by parsexsd; DO NOT EDIT.\n\npackage %s\n\n", g.pkg)
+	}
+
+	fmt.Fprintf(&res, `import (
+		"encoding/xml"
+		"time"
+		"github.com/rpoletaev/parsexsd/xsd"
+	`)
+	for _, spec := range imps.specs() {
+		fmt.Fprintln(&res, spec)
+	}
+	fmt.Fprintf(&res, ")\n")
+	body.WriteTo(&res)
+
+	buf, err := imports.Process("", res.Bytes(), &imports.Options{
+		Fragment:  true,
+		Comments:  true,
+		TabIndent: true,
+		TabWidth:  8,
+	})
+	if err != nil {
+		io.Copy(out, bytes.NewBuffer(res.Bytes()))
+		return err
+	}
+
+	if _, err := io.Copy(out, bytes.NewBuffer(buf)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g generator) execute(root *xsd.XmlTree, tt *template.Template, out io.Writer) error {
+	if root.Name != "unfairSupplier" {
+		if _, ok := g.types[root.Name]; ok {
+			return nil
+		}
+	}
+	if err := tt.Execute(out, root); err != nil {
+		return err
+	}
+
+	g.types[root.Name] = struct{}{}
+
+	for _, e := range root.Children {
+		if !primitiveType(e) && e.StructNeeded {
+			if err := g.execute(e, tt, out); err != nil {
+				return fmt.Errorf("%s: %v", e.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g generator) prepareTemplates(imps importSet) (*template.Template, error) {
+	typeName := func(name string) string {
+		if isImportedType(name) {
+			return imps.qualify(name)
+		}
+		if containsAllowedPackage(name) {
+			return name
+		}
+
+		if isBuiltinType(name) {
+			return name
+		}
+		return g.structName(name)
+	}
+
+	// Nillable elements are wrapped to keep xsi:nil, types of other
+	// packages are qualified
+	childType := func(e *xsd.XmlTree) string {
+		name := typeName(fieldType(e))
+		if g.foreign != nil {
+			if path, pkg := g.foreign(e); path != "" {
+				imps.add(path, pkg)
+				name = imps[path].local() + "." + name
+			}
+		}
+		if e.Nillable {
+			return "xsd.Nillable[" + name + "]"
+		}
+		return name
+	}
+
+	fmap := template.FuncMap{
+		"lint":        g.lint,
+		"lintTitle":   g.lintTitle,
+		"typeName":    typeName,
+		"childType":   childType,
+		"fieldType":   fieldType,
+		"checks":      g.checks,
+		"facets":      facetsLiteral,
+		"constraints": constraintsLiteral,
+		"validate":    func() bool { return g.validate },
+		"component":   component,
+	}
+
+	tt := template.New("yyy").Funcs(fmap)
+	if _, err := tt.Parse(attr); err != nil {
+		return nil, err
+	}
+	if _, err := tt.Parse(cdata); err != nil {
+		return nil, err
+	}
+	if _, err := tt.Parse(child); err != nil {
+		return nil, err
+	}
+	if _, err := tt.Parse(validate); err != nil {
+		return nil, err
+	}
+	if _, err := tt.Parse(elem); err != nil {
+		return nil, err
+	}
+	return tt, nil
+}
+
+// component describes the XSD component the struct is generated from, like
+// element export or complex type zfcs_contractType, it is read back by
+// diagnostics of PluginCompiler
+func component(t *xsd.XmlTree) string {
+	if !t.Root && t.Location != "" {
+		return "complex type " + t.Name
+	}
+	return "element " + t.Name
+}
+
+// structName returns name of the struct generated from the element or the
+// complex type
+func (g generator) structName(name string) string {
+	if g.prefix != "" {
+		name = g.prefix + strings.Title(name)
+	}
+	if g.exported {
+		name = strings.Title(name)
+	}
+	return g.lint(name)
+}
+
+func containsAllowedPackage(typeName string) bool {
+	return strings.HasPrefix(typeName, "time.") || strings.HasPrefix(typeName, "xsd.") || isImportedType(typeName)
+}
+
+func isBuiltinType(typeName string) bool {
+	clearType := strings.TrimPrefix(typeName, "[]")
+	for _, t := range builtinTypes {
+		if clearType == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// If this is a chardata field, the field type must point to a
+// struct, even if the element type is a built-in primitive.
+func fieldType(e *xsd.XmlTree) string {
+	if e.Cdata {
+		return e.Name
+	}
+	return e.Type
+}
+
+func primitiveType(e *xsd.XmlTree) bool {
+	if e.Cdata {
+		return false
+	}
+
+	if isBuiltinType(e.Type) {
+		return true
+	}
+	return false
+}
+
+// valueCheck is a single check of a struct field made by Validate method.
+// Fields with facets are checked by xsd.Facets, struct fields are validated
+// recursively, identity constraints are checked on elements which declare
+// them. Cond guards the check: zero values of optional fields are treated as
+// absent and nil values of nillable ones are not checked.
+type valueCheck struct {
+	Name        string
+	Field       string
+	Expr        string
+	Value       string
+	Cond        string
+	List        bool
+	Facets      *xsd.Facets
+	Constraints []xsd.IdentityConstraint
+}
+
+func (g generator) checks(e *xsd.XmlTree) []valueCheck {
+	var res []valueCheck
+	for _, a := range e.Attribs {
+		field := g.lintTitle(a.Name)
+		value, ok := checkValue(a.Type, "v."+field)
+		facets := valueFacets(a.Type, a.Facets)
+		if ok && facets != nil {
+			check := valueCheck{Name: a.Name, Field: field, Value: value, Facets: facets}
+			if a.Optional {
+				check.Cond = "!xsd.IsZero(v." + field + ")"
+			}
+			res = append(res, check)
+		}
+	}
+
+	for _, c := range e.Children {
+		check := valueCheck{Name: c.Name, Field: g.lintTitle(c.Name), List: c.List}
+		check.Expr = "v." + check.Field
+		if check.List {
+			check.Expr += "[i]"
+		}
+
+		var conds []string
+		if c.Nillable {
+			conds = append(conds, "!"+check.Expr+".Nil")
+			check.Expr += ".Value"
+		}
+		if c.Optional && !c.List {
+			conds = append(conds, "!xsd.IsZero("+check.Expr+")")
+		}
+		check.Cond = strings.Join(conds, " && ")
+
+		// constraints of inline elements are checked by their own structs
+		if len(c.Constraints) > 0 && !c.StructNeeded {
+			constraints := check
+			constraints.Constraints = c.Constraints
+			res = append(res, constraints)
+		}
+
+		if value, ok := checkValue(c.Type, check.Expr); ok && valueFacets(c.Type, c.Facets) != nil && !c.Cdata {
+			check.Value = value
+			check.Facets = valueFacets(c.Type, c.Facets)
+		} else if !structType(c) {
+			continue
+		}
+		res = append(res, check)
+	}
+
+	if facets := valueFacets(e.Type, e.Facets); e.Cdata && facets != nil {
+		field := g.lintTitle(e.Name)
+		if value, ok := checkValue(e.Type, "v."+field); ok {
+			res = append(res, valueCheck{Name: e.Name, Field: field, Value: value, Facets: facets})
+		}
+	}
+
+	if len(e.Constraints) > 0 {
+		res = append(res, valueCheck{Name: e.Name, Expr: "v", Constraints: e.Constraints})
+	}
+	return res
+}
+
+// constraintsLiteral returns Go composite literal of identity constraints
+func constraintsLiteral(cc []xsd.IdentityConstraint) string {
+	var b strings.Builder
+	b.WriteString("[]xsd.IdentityConstraint{\n")
+	for _, c := range cc {
+		fmt.Fprintf(&b, "{Kind: %q, Name: %q, ", c.Kind, c.Name)
+		if c.Refer != "" {
+			fmt.Fprintf(&b, "Refer: %q, ", c.Refer)
+		}
+		fmt.Fprintf(&b, "Selector: xsd.Selector{XPath: %q}, Fields: []xsd.Field{", c.Selector.XPath)
+		for i, f := range c.Fields {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "{XPath: %q}", f.XPath)
+		}
+		b.WriteString("}},\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// facetsLiteral returns Go composite literal of the facets. Patterns which
+// can not be translated into Go regexp are skipped with a warning.
+func facetsLiteral(f *xsd.Facets) string {
+	var fields []string
+	if f.WhiteSpace != "" {
+		fields = append(fields, fmt.Sprintf("WhiteSpace: %q", f.WhiteSpace))
+	}
+	if f.Length > 0 {
+		fields = append(fields, fmt.Sprintf("Length: %d", f.Length))
+	}
+	if f.MinLength > 0 {
+		fields = append(fields, fmt.Sprintf("MinLength: %d", f.MinLength))
+	}
+	if f.MaxLength > 0 {
+		fields = append(fields, fmt.Sprintf("MaxLength: %d", f.MaxLength))
+	}
+
+	var patterns []string
+	for _, p := range f.Patterns {
+		if _, err := xsd.TranslatePattern(p); err != nil {
+			log.WithField("pattern", p).Warnf("Pattern is skipped: %v", err)
+			continue
+		}
+		patterns = append(patterns, quote(p))
+	}
+	if len(patterns) > 0 {
+		fields = append(fields, "Patterns: []string{"+strings.Join(patterns, ", ")+"}")
+	}
+	if len(f.Enumeration) > 0 {
+		values := make([]string, len(f.Enumeration))
+		for i, e := range f.Enumeration {
+			values[i] = quote(e)
+		}
+		fields = append(fields, "Enumeration: []string{"+strings.Join(values, ", ")+"}")
+	}
+	return "xsd.Facets{" + strings.Join(fields, ", ") + "}"
+}
+
+// quote returns raw string literal if possible, which keeps backslashes of
+// patterns readable
+func quote(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return fmt.Sprintf("%q", s)
+	}
+	return "`" + s + "`"
+}
+
+// checkValue returns expression converting the field value into a string
+// checked by facets. Numbers are formatted in their canonical lexical form,
+// see valueFacets.
+func checkValue(typeName, expr string) (string, bool) {
+	switch typeName {
+	case "string", "xsd.Token", "xsd.NormalizedString":
+		return "string(" + expr + ")", true
+	case "int", "int8", "int16", "int32", "int64":
+		return "strconv.FormatInt(int64(" + expr + "), 10)", true
+	case "uint", "byte", "uint8", "uint16", "uint32", "uint64":
+		return "strconv.FormatUint(uint64(" + expr + "), 10)", true
+	case "float32":
+		return "strconv.FormatFloat(float64(" + expr + "), 'f', -1, 32)", true
+	case "float64":
+		return "strconv.FormatFloat(" + expr + ", 'f', -1, 64)", true
+	}
+	return "", false
+}
+
+// valueFacets returns facets checked against the value of checkValue, nil
+// if there is nothing to check. The lexical form of numbers is lost when
+// they are decoded, so enumeration values of numbers are turned into the
+// canonical form and patterns of floating point numbers, which are often
+// written with trailing zeros like 100.50, are skipped with a warning.
+func valueFacets(typeName string, f *xsd.Facets) *xsd.Facets {
+	if f == nil {
+		return nil
+	}
+	var canonical func(string) (string, error)
+	switch typeName {
+	case "int", "int8", "int16", "int32", "int64":
+		canonical = func(s string) (string, error) {
+			i, err := strconv.ParseInt(s, 10, 64)
+			return strconv.FormatInt(i, 10), err
+		}
+	case "uint", "byte", "uint8", "uint16", "uint32", "uint64":
+		canonical = func(s string) (string, error) {
+			u, err := strconv.ParseUint(s, 10, 64)
+			return strconv.FormatUint(u, 10), err
+		}
+	case "float32", "float64":
+		bitSize := 64
+		if typeName == "float32" {
+			bitSize = 32
+		}
+		canonical = func(s string) (string, error) {
+			f, err := strconv.ParseFloat(s, bitSize)
+			return strconv.FormatFloat(f, 'f', -1, bitSize), err
+		}
+	default:
+		return f
+	}
+
+	res := *f
+	if strings.HasPrefix(typeName, "float") && len(f.Patterns) > 0 {
+		for _, p := range f.Patterns {
+			log.WithField("pattern", p).Warnf("Pattern of %s is skipped, lexical form of numbers is not kept", typeName)
+		}
+		res.Patterns = nil
+	}
+	res.Enumeration = nil
+	for _, e := range f.Enumeration {
+		value, err := canonical(strings.TrimSpace(e))
+		if err != nil {
+			log.WithField("enumeration", e).Warnf("Enumeration value is skipped: %v", err)
+			continue
+		}
+		res.Enumeration = append(res.Enumeration, value)
+	}
+	if res.IsEmpty() {
+		return nil
+	}
+	return &res
+}
+
+// structType returns true if the field type is a generated struct
+func structType(e *xsd.XmlTree) bool {
+	return !primitiveType(e) && !containsAllowedPackage(fieldType(e))
+}
+
+func (g generator) lint(s string) string {
+	return dashToCamel(squish(g.initialisms.Replace(s)))
+}
+
+func (g generator) lintTitle(s string) string {
+	return g.lint(strings.Title(s))
+}
+
+func squish(s string) string {
+	return strings.Replace(s, " ", "", -1)
+}
+
+func dashToCamel(name string) string {
+	name = strings.Replace(name, "_", "-", -1)
+	name = strings.Replace(name, ".", "-", -1)
+
+	s := strings.Split(name, "-")
+	if len(s) > 1 {
+		for i := 1; i < len(s); i++ {
+			s[i] = strings.Title(s[i])
+		}
+		return strings.Join(s, "")
+	}
+	return name
+}
@@ -0,0 +1,253 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSchema writes the schema with the content into the directory and
+// returns its path
+func writeSchema(t *testing.T, dir, name, content string) string {
+	src := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">` + content + `</xs:schema>`
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// generatedDir returns a new directory in testdata of the package for
+// generated code and its import path, so generated code imports the xsd
+// package of the tree
+func generatedDir(t *testing.T) (string, string) {
+	if testing.Short() {
+		t.Skip("generated code is not built in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command is not found")
+	}
+	if err := os.MkdirAll("testdata", 0777); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("testdata", "run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+		os.Remove("testdata")
+	})
+	return dir, "github.com/rpoletaev/parsexsd/gen/" + filepath.ToSlash(dir)
+}
+
+// runGenerated builds the generated files in the directory of generatedDir
+// together with the main source into a program and returns its output
+func runGenerated(t *testing.T, dir string, files []File, main string) string {
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.Content, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main_test_run.go"), []byte(main), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command("go", "run", "./"+filepath.ToSlash(dir)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated code does not run: %v\n%s", err, out)
+	}
+	return string(out)
+}
+
+func TestGeneratedValidateNumbers(t *testing.T) {
+	dir := t.TempDir()
+	input := writeSchema(t, dir, "prices.xsd", `<xs:element name="prices"><xs:complexType><xs:sequence>
+		<xs:element name="ratio" minOccurs="0"><xs:simpleType><xs:restriction base="xs:float">
+			<xs:pattern value="\d+\.\d"/>
+		</xs:restriction></xs:simpleType></xs:element>
+		<xs:element name="amount" minOccurs="0"><xs:simpleType><xs:restriction base="xs:decimal">
+			<xs:pattern value="\d+\.\d{2}"/>
+		</xs:restriction></xs:simpleType></xs:element>
+		<xs:element name="rate" minOccurs="0"><xs:simpleType><xs:restriction base="xs:decimal">
+			<xs:enumeration value="0.50"/>
+			<xs:enumeration value="1.0"/>
+		</xs:restriction></xs:simpleType></xs:element>
+		<xs:element name="count" minOccurs="0"><xs:simpleType><xs:restriction base="xs:int">
+			<xs:pattern value="\d{1,2}"/>
+		</xs:restriction></xs:simpleType></xs:element>
+		<xs:element name="level" minOccurs="0"><xs:simpleType><xs:restriction base="xs:int">
+			<xs:enumeration value="+1"/>
+			<xs:enumeration value="02"/>
+		</xs:restriction></xs:simpleType></xs:element>
+	</xs:sequence></xs:complexType></xs:element>`)
+
+	schemas, err := Load([]string{input}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schemas, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const main = `package main
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+func main() {
+	for _, doc := range []string{
+		"<prices><ratio>0.1</ratio></prices>",
+		"<prices><amount>100.50</amount></prices>",
+		"<prices><rate>0.5</rate></prices>",
+		"<prices><rate>1.00</rate></prices>",
+		"<prices><rate>2</rate></prices>",
+		"<prices><count>42</count></prices>",
+		"<prices><count>420</count></prices>",
+		"<prices><level>1</level></prices>",
+		"<prices><level>2</level></prices>",
+		"<prices><level>3</level></prices>",
+	} {
+		var v Prices
+		if err := xml.Unmarshal([]byte(doc), &v); err != nil {
+			fmt.Println(doc, err)
+			continue
+		}
+		fmt.Println(doc, v.Validate() == nil)
+	}
+}
+`
+	out, _ := generatedDir(t)
+	got := strings.Split(strings.TrimSpace(runGenerated(t, out, files, main)), "\n")
+	want := []string{
+		"<prices><ratio>0.1</ratio></prices> true",
+		"<prices><amount>100.50</amount></prices> true",
+		"<prices><rate>0.5</rate></prices> true",
+		"<prices><rate>1.00</rate></prices> true",
+		"<prices><rate>2</rate></prices> false",
+		"<prices><count>42</count></prices> true",
+		"<prices><count>420</count></prices> false",
+		"<prices><level>1</level></prices> true",
+		"<prices><level>2</level></prices> true",
+		"<prices><level>3</level></prices> false",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("output is %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("validation is %s, want %s", got[i], want[i])
+		}
+	}
+}
+
+func TestGenerateInitialisms(t *testing.T) {
+	dir := t.TempDir()
+	input := writeSchema(t, dir, "org.xsd", `<xs:element name="org"><xs:complexType><xs:sequence>
+		<xs:element name="innCode" type="xs:string"/>
+		<xs:element name="id" type="xs:string"/>
+	</xs:sequence></xs:complexType></xs:element>`)
+	schemas, err := Load([]string{input}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		initialisms map[string]string
+		field       string
+	}{
+		{nil, "InnCode "},
+		{map[string]string{"Inn": "INN"}, "INNCode "},
+	}
+	// generations run concurrently must not share initialisms
+	results := make(chan error, 10*len(tests))
+	for i := 0; i < 10; i++ {
+		for _, tt := range tests {
+			go func(initialisms map[string]string, field string) {
+				opts := DefaultOptions()
+				opts.Initialisms = initialisms
+				files, err := Generate(schemas, opts)
+				if err == nil && !strings.Contains(string(files[0].Content), field) {
+					err = fmt.Errorf("field %s is not generated with initialisms %v:\n%s", field, initialisms, files[0].Content)
+				}
+				results <- err
+			}(tt.initialisms, tt.field)
+		}
+	}
+	for i := 0; i < cap(results); i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestGeneratedValidateChoice(t *testing.T) {
+	dir := t.TempDir()
+	input := writeSchema(t, dir, "party.xsd", `<xs:simpleType name="code"><xs:restriction base="xs:string">
+		<xs:minLength value="9"/>
+	</xs:restriction></xs:simpleType>
+	<xs:element name="party"><xs:complexType><xs:sequence>
+		<xs:choice>
+			<xs:element name="inn" type="code"/>
+			<xs:element name="ogrn" type="code"/>
+		</xs:choice>
+	</xs:sequence></xs:complexType></xs:element>`)
+
+	schemas, err := Load([]string{input}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := Generate(schemas, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const main = `package main
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+func main() {
+	for _, doc := range []string{
+		"<party><inn>123456789</inn></party>",
+		"<party><ogrn>123456789</ogrn></party>",
+		"<party><inn>1234</inn></party>",
+	} {
+		var v Party
+		if err := xml.Unmarshal([]byte(doc), &v); err != nil {
+			fmt.Println(doc, err)
+			continue
+		}
+		fmt.Println(doc, v.Validate() == nil)
+	}
+}
+`
+	out, _ := generatedDir(t)
+	got := strings.Split(strings.TrimSpace(runGenerated(t, out, files, main)), "\n")
+	// absent alternatives of the choice are not checked
+	want := []string{
+		"<party><inn>123456789</inn></party> true",
+		"<party><ogrn>123456789</ogrn></party> true",
+		"<party><inn>1234</inn></party> false",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("output is %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("validation is %s, want %s", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package gen
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+// LoadOptions are options of reading schemas
+type LoadOptions struct {
+	// Catalogs are OASIS XML catalogs mapping schema locations to local
+	// copies
+	Catalogs []string
+	// VersionFile is the schema next to an entry point the version of the
+	// bundle is read from, the version of the first schema it is detected
+	// for is used if it is empty
+	VersionFile string
+	// VersionDetectors detect versions of schemas, they are
+	// xsd.DefaultVersionDetectors if it is nil
+	VersionDetectors []xsd.VersionDetector
+}
+
+// Schema is an entry point schema followed by all schemas it imports and
+// includes, the version of the bundle is set on the first one
+type Schema struct {
+	// Name is the input the schema is read from, schemas of zip archives
+	// are named like bundle.zip#fcsExport.xsd
+	Name string
+	// Location is the path of the schema in its directory or archive
+	Location string
+	Schemas  []xsd.Schema
+	// Sources are files the schemas are read from, zip archives are files
+	// of their schemas
+	Sources []Source
+}
+
+// TargetNamespace returns the target namespace of the entry point schema
+func (s Schema) TargetNamespace() string {
+	return s.Schemas[0].TargetNamespace
+}
+
+// Version returns version of the bundle, it is nil if it was not read
+func (s Schema) Version() xsd.Version {
+	return s.Schemas[0].Version
+}
+
+// Load reads schemas given by names, which are XSD files, directories or zip
+// archives of schema bundles. An entry point of an archive is given after
+// '#' by its path or base name, without it all root schemas of the archive
+// are read, as all root schemas of a directory are.
+func Load(names []string, opts LoadOptions) ([]Schema, error) {
+	in, err := newInputs(opts.Catalogs, opts.VersionDetectors)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var res []Schema
+	for _, name := range names {
+		inputs, err := in.expand(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, input := range inputs {
+			schemas, err := input.loader.Load(input.location)
+			if err != nil {
+				return nil, err
+			}
+			if opts.VersionFile != "" {
+				if schemas[0].Version, err = input.readVersion(opts.VersionFile); err != nil {
+					return nil, err
+				}
+			} else {
+				schemas[0].Version = bundleVersion(schemas)
+			}
+			sources, err := input.sources(schemas)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, Schema{Name: input.name, Location: input.location, Schemas: schemas, Sources: sources})
+		}
+	}
+	return res, nil
+}
+
+// ReadVersion reads version from the file of the bundle directory or zip
+// archive, the file may be anywhere inside of the archive
+func ReadVersion(bundle, file string) (xsd.Version, error) {
+	if archive, _ := splitArchive(bundle); archive == "" {
+		return xsd.GetSchemaVersion(filepath.Join(bundle, file))
+	}
+
+	l, err := xsd.NewZipLoader(bundle)
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	location, err := l.Find(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", bundle, err)
+	}
+	return schemaInput{loader: l, location: location}.readVersion(filepath.Base(location))
+}
+
+// schemaInput is an entry point schema and the loader reading it
+type schemaInput struct {
+	loader   *xsd.Loader
+	location string
+	name     string
+	// archive is the zip archive or the directory of the loader
+	archive string
+	// roots is true if the input is one of all root schemas of the
+	// directory, which are found again when schemas are added to it
+	roots bool
+}
+
+// inputs reads schemas given by the user and keeps archives open
+type inputs struct {
+	files    *xsd.Loader
+	archives map[string]*xsd.Loader // zip archives and directories
+}
+
+func newInputs(catalogs []string, detectors []xsd.VersionDetector) (*inputs, error) {
+	l := xsd.NewLoader(nil)
+	if detectors != nil {
+		l.VersionDetectors = detectors
+	}
+	for _, c := range catalogs {
+		if err := l.AddCatalog(c); err != nil {
+			return nil, err
+		}
+	}
+	return &inputs{files: l, archives: make(map[string]*xsd.Loader)}, nil
+}
+
+// Close closes opened archives
+func (in *inputs) Close() {
+	for _, l := range in.archives {
+		l.Close()
+	}
+}
+
+// expand returns entry point schemas of the input
+func (in *inputs) expand(name string) ([]schemaInput, error) {
+	archive, entry := splitArchive(name)
+	if archive == "" {
+		if fi, err := os.Stat(name); err != nil || !fi.IsDir() {
+			return []schemaInput{{in.files, name, name, "", false}}, nil
+		}
+		archive = name
+	}
+
+	l, err := in.archive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []string
+	if entry != "" {
+		location, err := l.Find(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", archive, err)
+		}
+		locations = append(locations, location)
+	} else if locations, err = l.Roots(); err != nil {
+		return nil, fmt.Errorf("%s: %v", archive, err)
+	}
+
+	res := make([]schemaInput, len(locations))
+	for i, location := range locations {
+		res[i] = schemaInput{l, location, archive + "#" + location, archive, false}
+		if !isArchive(archive) {
+			res[i].name = filepath.Join(archive, filepath.FromSlash(location))
+			res[i].roots = entry == ""
+		}
+	}
+	return res, nil
+}
+
+// archive returns loader of the zip archive or the directory
+func (in *inputs) archive(name string) (*xsd.Loader, error) {
+	if l, ok := in.archives[name]; ok {
+		return l, nil
+	}
+
+	l := xsd.NewLoader(os.DirFS(name))
+	if isArchive(name) {
+		var err error
+		if l, err = xsd.NewZipLoader(name); err != nil {
+			return nil, err
+		}
+	}
+	l.Catalog = in.files.Catalog
+	l.VersionDetectors = in.files.VersionDetectors
+	in.archives[name] = l
+	return l, nil
+}
+
+// readVersion reads version of the schema bundle from the file with the
+// given name next to the schema
+func (s schemaInput) readVersion(versionFile string) (xsd.Version, error) {
+	name := filepath.Join(filepath.Dir(s.location), versionFile)
+	f, err := s.loader.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	version, err := xsd.ReadSchemaVersion(f, s.loader.VersionDetectors...)
+	if err != nil {
+		return nil, fmt.Errorf("could not read version of %s: %v", name, err)
+	}
+	return version, nil
+}
+
+// bundleVersion returns the version of the first schema it is detected for
+func bundleVersion(schemas []xsd.Schema) xsd.Version {
+	for _, s := range schemas {
+		if s.Version != nil {
+			return s.Version
+		}
+	}
+	return nil
+}
+
+// splitArchive splits input name into the zip archive and the entry point
+// inside of it, archive is empty if the input is not a zip archive
+func splitArchive(name string) (string, string) {
+	archive, entry := name, ""
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		archive, entry = name[:i], name[i+1:]
+	}
+	if !isArchive(archive) {
+		return "", ""
+	}
+	return archive, entry
+}
+
+// sources returns files the schemas of the input are read from, the
+// directory itself is a source of its roots
+func (s schemaInput) sources(schemas []xsd.Schema) ([]Source, error) {
+	var res []Source
+	if s.roots {
+		src, err := HashFile(s.archive)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, src)
+	}
+	for _, schema := range schemas {
+		u, err := url.Parse(schema.Location)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case u.Scheme == "fs" && isArchive(s.archive):
+			src, err := HashFile(s.archive)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, src)
+		case u.Scheme == "fs":
+			res = append(res, Source{Path: filepath.Join(s.archive, filepath.FromSlash(u.Path)), Hash: schema.Hash})
+		case u.Scheme == "file":
+			res = append(res, Source{Path: filepath.FromSlash(u.Path), Hash: schema.Hash})
+		default:
+			res = append(res, Source{Path: schema.Location, Hash: schema.Hash})
+		}
+	}
+	return res, nil
+}
+
+func isArchive(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
@@ -0,0 +1,42 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+// writeRegistry generates the xsd.TypeRegistry variable of the root
+// elements, plugins export it for xsd.OpenPlugin
+func (g generator) writeRegistry(out io.Writer, roots []*xsd.XmlTree) {
+	fmt.Fprintf(out, "// %s maps root elements of the schemas to their structs\n", xsd.RegistrySymbol)
+	fmt.Fprintf(out, "var %s = xsd.TypeRegistry{\n", xsd.RegistrySymbol)
+	fmt.Fprintf(out, "Contract: %d,\n", xsd.RegistryContract)
+	if g.version != nil {
+		fmt.Fprintf(out, "Version: %s,\n", versionLiteral(g.version))
+	}
+	fmt.Fprintf(out, "Elements: map[xml.Name]func() interface{}{\n")
+
+	seen := make(map[string]bool)
+	for _, t := range roots {
+		key := t.Namespace + " " + t.Name
+		if !t.Root || seen[key] {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(out, "{Space: %q, Local: %q}: func() interface{} { return new(%s) },\n",
+			t.Namespace, t.Name, g.structName(t.Name))
+	}
+	fmt.Fprintf(out, "},\n}\n")
+}
+
+// schemasVersion returns the first detected version of the schemas
+func schemasVersion(schemas []Schema) xsd.Version {
+	for _, s := range schemas {
+		if v := s.Version(); v != nil {
+			return v
+		}
+	}
+	return nil
+}
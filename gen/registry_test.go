@@ -0,0 +1,42 @@
+package gen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+func TestWriteRegistry(t *testing.T) {
+	version, _ := xsd.ParseVersion("6.4")
+	g := generator{exported: true, prefix: "zfcs", initialisms: newInitialisms(nil), version: version}
+	roots := []*xsd.XmlTree{
+		{Name: "export", Namespace: "urn:export", Root: true},
+		{Name: "contractType", Namespace: "urn:export"},
+		{Name: "export", Namespace: "urn:export", Root: true},
+		{Name: "idList", Root: true},
+	}
+
+	var buf bytes.Buffer
+	g.writeRegistry(&buf, roots)
+	want := `// Registry maps root elements of the schemas to their structs
+var Registry = xsd.TypeRegistry{
+Contract: 1,
+Version: xsd.Version{6, 4},
+Elements: map[xml.Name]func() interface{}{
+{Space: "urn:export", Local: "export"}: func() interface{} { return new(ZfcsExport) },
+{Space: "", Local: "idList"}: func() interface{} { return new(ZfcsIDList) },
+},
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeRegistry() =\n%s\nwant\n%s", got, want)
+	}
+
+	g.version = nil
+	buf.Reset()
+	g.writeRegistry(&buf, nil)
+	if bytes.Contains(buf.Bytes(), []byte("Version:")) {
+		t.Errorf("registry without version is\n%s", buf.String())
+	}
+}
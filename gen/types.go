@@ -0,0 +1,248 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+	"golang.org/x/tools/go/packages"
+)
+
+// Go types of XSD types may be overridden by types of any package, given by
+// import path and type name, e.g. github.com/google/uuid.UUID. Such types
+// are imported by generated code and must be decoded and encoded by
+// encoding/xml.
+
+// isImportedType returns true if the type is given with its import path
+func isImportedType(name string) bool {
+	return strings.Contains(name, "/")
+}
+
+// splitImportedType returns import path and name of the type
+func splitImportedType(name string) (string, string) {
+	i := strings.LastIndex(name, ".")
+	if i < strings.LastIndex(name, "/") {
+		return name, ""
+	}
+	return name[:i], name[i+1:]
+}
+
+// importSpec is an imported package of overridden types
+type importSpec struct {
+	name  string
+	alias string
+}
+
+// importSet holds packages of overridden types by import path
+type importSet map[string]importSpec
+
+// add adds the package, it gets an alias if its name is already taken
+func (s importSet) add(path, name string) {
+	if _, ok := s[path]; ok {
+		return
+	}
+
+	taken := map[string]bool{"xsd": true, "time": true, "strconv": true}
+	for _, spec := range s {
+		taken[spec.local()] = true
+	}
+	spec := importSpec{name: name}
+	for i := 2; taken[spec.local()]; i++ {
+		spec.alias = name + strconv.Itoa(i)
+	}
+	s[path] = spec
+}
+
+func (spec importSpec) local() string {
+	if spec.alias != "" {
+		return spec.alias
+	}
+	return spec.name
+}
+
+// qualify returns the type qualified by the local name of its package
+func (s importSet) qualify(name string) string {
+	path, typeName := splitImportedType(name)
+	spec, ok := s[path]
+	if !ok {
+		return name
+	}
+	return spec.local() + "." + typeName
+}
+
+// specs returns import specs of the packages sorted by path
+func (s importSet) specs() []string {
+	paths := make([]string, 0, len(s))
+	for path := range s {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	res := make([]string, len(paths))
+	for i, path := range paths {
+		res[i] = strconv.Quote(path)
+		if alias := s[path].alias; alias != "" {
+			res[i] = alias + " " + res[i]
+		}
+	}
+	return res
+}
+
+// typeUse is an overridden type and whether it is used as text, which is
+// the case of attributes and character data
+type typeUse struct {
+	name string
+	text bool
+}
+
+// collectImportedTypes returns overridden types used by the trees
+func collectImportedTypes(roots []*xsd.XmlTree) []typeUse {
+	seen := make(map[typeUse]bool)
+	var res []typeUse
+	add := func(name string, text bool) {
+		u := typeUse{name, text}
+		if isImportedType(name) && !seen[u] {
+			seen[u] = true
+			res = append(res, u)
+		}
+	}
+
+	var visit func(t *xsd.XmlTree)
+	visit = func(t *xsd.XmlTree) {
+		for _, a := range t.Attribs {
+			add(a.Type, true)
+		}
+		if t.Cdata {
+			add(t.Type, true)
+		}
+		for _, c := range t.Children {
+			if !c.Cdata {
+				add(c.Type, false)
+			}
+			visit(c)
+		}
+	}
+	for _, t := range roots {
+		visit(t)
+	}
+	return res
+}
+
+// resolveImports loads packages of overridden types used by the trees and
+// checks that the types can be decoded and encoded by encoding/xml
+func resolveImports(roots []*xsd.XmlTree) (importSet, error) {
+	imps := make(importSet)
+	uses := collectImportedTypes(roots)
+	if len(uses) == 0 {
+		return imps, nil
+	}
+
+	var paths []string
+	for _, u := range uses {
+		path, typeName := splitImportedType(u.name)
+		if typeName == "" {
+			return nil, fmt.Errorf("type %q has no name after the import path", u.name)
+		}
+		paths = append(paths, path)
+	}
+
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedTypes}, paths...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load packages of overridden types: %v", err)
+	}
+	byPath := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("could not load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		byPath[pkg.PkgPath] = pkg
+	}
+
+	for _, u := range uses {
+		path, typeName := splitImportedType(u.name)
+		pkg, ok := byPath[path]
+		if !ok {
+			return nil, fmt.Errorf("could not load package %s", path)
+		}
+		obj, ok := pkg.Types.Scope().Lookup(typeName).(*types.TypeName)
+		if !ok || !obj.Exported() {
+			return nil, fmt.Errorf("package %s has no exported type %s", path, typeName)
+		}
+		if err := checkMarshalling(obj.Type(), u.text); err != nil {
+			return nil, fmt.Errorf("type %s can not be used: %v", u.name, err)
+		}
+		imps.add(path, pkg.Name)
+	}
+	return imps, nil
+}
+
+// checkMarshalling checks the type is decoded and encoded by encoding/xml,
+// which is true for basic types and types implementing text or XML
+// marshalling interfaces
+func checkMarshalling(t types.Type, text bool) error {
+	if _, ok := t.Underlying().(*types.Basic); ok {
+		return nil
+	}
+
+	// methods of values are used through pointers to fields
+	ptr := types.NewPointer(t)
+	marshal, unmarshal := "MarshalXML", "UnmarshalXML"
+	if text {
+		marshal, unmarshal = "MarshalXMLAttr", "UnmarshalXMLAttr"
+	}
+	if !hasMethod(ptr, "MarshalText", "func() ([]byte, error)") && !hasMethod(ptr, marshal, xmlSignatures[marshal]) {
+		return fmt.Errorf("it implements neither encoding.TextMarshaler nor xml.%s", marshalerName[marshal])
+	}
+	if !hasMethod(ptr, "UnmarshalText", "func([]byte) error") && !hasMethod(ptr, unmarshal, xmlSignatures[unmarshal]) {
+		return fmt.Errorf("it implements neither encoding.TextUnmarshaler nor xml.%s", marshalerName[unmarshal])
+	}
+	return nil
+}
+
+var (
+	xmlSignatures = map[string]string{
+		"MarshalXML":       "func(*encoding/xml.Encoder, encoding/xml.StartElement) error",
+		"UnmarshalXML":     "func(*encoding/xml.Decoder, encoding/xml.StartElement) error",
+		"MarshalXMLAttr":   "func(encoding/xml.Name) (encoding/xml.Attr, error)",
+		"UnmarshalXMLAttr": "func(encoding/xml.Attr) error",
+	}
+	marshalerName = map[string]string{
+		"MarshalXML":       "Marshaler",
+		"UnmarshalXML":     "Unmarshaler",
+		"MarshalXMLAttr":   "MarshalerAttr",
+		"UnmarshalXMLAttr": "UnmarshalerAttr",
+	}
+)
+
+// hasMethod returns true if the method set of the type has the method with
+// the signature, parameter names are ignored
+func hasMethod(t types.Type, name, signature string) bool {
+	sel := types.NewMethodSet(t).Lookup(nil, name)
+	if sel == nil {
+		return false
+	}
+	return signatureString(sel.Type().(*types.Signature)) == signature
+}
+
+func signatureString(sig *types.Signature) string {
+	tuple := func(t *types.Tuple) []string {
+		res := make([]string, t.Len())
+		for i := range res {
+			res[i] = types.TypeString(t.At(i).Type(), nil)
+		}
+		return res
+	}
+
+	s := "func(" + strings.Join(tuple(sig.Params()), ", ") + ")"
+	switch results := tuple(sig.Results()); len(results) {
+	case 0:
+	case 1:
+		s += " " + results[0]
+	default:
+		s += " (" + strings.Join(results, ", ") + ")"
+	}
+	return s
+}
@@ -0,0 +1,239 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/rpoletaev/parsexsd/xsd"
+)
+
+// DispatchFile is the name of the dispatcher generated by GenerateVersions
+const DispatchFile = "dispatch.go"
+
+// dispatch is the dispatcher of documents to structs of their versions
+var dispatch = template.Must(template.New("dispatch").Parse(`// Code generated by parsexsd; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/rpoletaev/parsexsd/xsd"
+{{ range .Versions }}
+	"{{ .ImportPath }}"{{ end }}
+)
+
+// Decode reads the root element and the {{ .Attribute }} attribute of the
+// document and decodes it into the struct of its schema version
+func Decode(r io.Reader) (interface{}, error) {
+	return xsd.DecodeVersioned(r, {{ printf "%q" .Attribute }}, New)
+}
+
+// New returns pointer to a new struct of the root element of the schema
+// version. Versions are decoded by the latest package not greater than them,
+// documents without the version by the latest package.
+func New(element xml.Name, version xsd.Version) (interface{}, error) {
+	if version == nil {
+		version = {{ .Latest }}
+	}
+
+	switch {
+{{- range .Versions }}
+	case ({{ .Range }}).Contains(version):
+		switch element {
+{{- $pkg := .Package }}{{ range .Roots }}
+		case xml.Name{Space: {{ printf "%q" .Space }}, Local: {{ printf "%q" .Local }}}:
+			return new({{ $pkg }}.{{ .Type }}), nil{{ end }}
+		}
+{{- end }}
+	}
+	return nil, fmt.Errorf("no %s element of schema version %s", element.Local, version)
+}
+`))
+
+type dispatchVersion struct {
+	Package, ImportPath, Range string
+	Roots                      []dispatchRoot
+}
+
+type dispatchRoot struct {
+	Space, Local, Type string
+}
+
+// VersionPackage returns the name of the package of the schema version, like
+// v6_4
+func VersionPackage(v xsd.Version) string {
+	return "v" + strings.Replace(v.String(), ".", "_", -1)
+}
+
+// GenerateVersions generates schemas of several versions into sibling
+// packages named by VersionPackage, every one in the directory of its name,
+// and the dispatcher of the package of options decoding documents into
+// structs of their versions. Schemas are grouped by their versions, which
+// must be detected. With Converters of options functions converting structs
+// of every version to the next one are generated into the package of
+// options, like convert_v4_4_v6_4.go, fields they do not convert are listed
+// in the report next to them, like convert_v4_4_v6_4.txt.
+func GenerateVersions(schemas []Schema, opts Options) ([]File, error) {
+	if opts.ImportPath == "" {
+		return nil, fmt.Errorf("import path of the output is needed by the dispatcher of versions")
+	}
+	if opts.Layout == LayoutNamespace {
+		return nil, fmt.Errorf("versions are not generated with %q layout", opts.Layout)
+	}
+	if !opts.Exported {
+		return nil, fmt.Errorf("structs of versions must be exported to be dispatched")
+	}
+
+	var versions []xsd.Version
+	groups := make(map[string][]Schema)
+	for _, s := range schemas {
+		v := s.Version()
+		if v == nil {
+			return nil, fmt.Errorf("version of %s is not detected", s.Name)
+		}
+		pkg := VersionPackage(v)
+		if _, ok := groups[pkg]; !ok {
+			versions = append(versions, v)
+		}
+		groups[pkg] = append(groups[pkg], s)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Less(versions[j]) })
+
+	g := generator{
+		prefix:      opts.Prefix,
+		exported:    opts.Exported,
+		initialisms: newInitialisms(opts.Initialisms),
+	}
+	attribute := opts.VersionAttribute
+	if attribute == "" {
+		attribute = "schemeVersion"
+	}
+	data := struct {
+		Package, Attribute, Latest string
+		Versions                   []dispatchVersion
+	}{
+		Package:   opts.Package,
+		Attribute: attribute,
+		Latest:    versionLiteral(versions[len(versions)-1]),
+	}
+
+	var files []File
+	var structs []versionStructs
+	for i, v := range versions {
+		pkg := VersionPackage(v)
+		vopts := opts
+		vopts.Package = pkg
+		vopts.Namespaces = nil
+
+		pkgFiles, err := Generate(groups[pkg], vopts)
+		if err != nil {
+			return nil, fmt.Errorf("version %s: %v", v, err)
+		}
+		for _, f := range pkgFiles {
+			f.Name = filepath.Join(pkg, f.Name)
+			files = append(files, f)
+		}
+		if opts.Converters {
+			var all []xsd.Schema
+			for _, s := range groups[pkg] {
+				all = append(all, s.Schemas...)
+			}
+			structs = append(structs, collectStructs(pkg, Build(all, opts.BuildOptions)))
+		}
+
+		r := xsd.VersionRange{Min: v}
+		if i+1 < len(versions) {
+			r.Max, r.MaxExclusive = versions[i+1], true
+		}
+		data.Versions = append(data.Versions, dispatchVersion{
+			Package:    pkg,
+			ImportPath: path.Join(opts.ImportPath, pkg),
+			Range:      rangeLiteral(r),
+			Roots:      g.dispatchRoots(groups[pkg]),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := dispatch.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format dispatcher: %v", err)
+	}
+	files = append(files, File{Name: DispatchFile, Package: opts.Package, Content: content})
+
+	g.pkg = opts.Package
+	for i := 1; i < len(structs); i++ {
+		f, report, err := g.convertFile(structs[i-1], structs[i], opts.ImportPath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "Fields of %s which are not converted to %s by %s:\n\n", structs[i-1].pkg, structs[i].pkg, f.Name)
+		for _, r := range report {
+			fmt.Fprintln(&buf, r)
+		}
+		if len(report) > 0 {
+			log.Warnf("%d fields of %s are not converted to %s, see %s", len(report), structs[i-1].pkg, structs[i].pkg, reportName(f.Name))
+		}
+		files = append(files, File{Name: reportName(f.Name), Package: opts.Package, Content: buf.Bytes()})
+	}
+	return files, nil
+}
+
+// reportName returns name of the report of the converters file
+func reportName(name string) string {
+	return strings.TrimSuffix(name, ".go") + ".txt"
+}
+
+// dispatchRoots returns root elements of the schemas with their structs
+func (g generator) dispatchRoots(schemas []Schema) []dispatchRoot {
+	var roots []dispatchRoot
+	seen := make(map[string]bool)
+	for _, s := range schemas {
+		for _, schema := range s.Schemas {
+			for _, e := range schema.Elements {
+				key := schema.TargetNamespace + " " + e.Name
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				roots = append(roots, dispatchRoot{
+					Space: schema.TargetNamespace,
+					Local: e.Name,
+					Type:  g.structName(e.Name),
+				})
+			}
+		}
+	}
+	return roots
+}
+
+func versionLiteral(v xsd.Version) string {
+	parts := make([]string, len(v))
+	for i, n := range v {
+		parts[i] = fmt.Sprint(n)
+	}
+	return "xsd.Version{" + strings.Join(parts, ", ") + "}"
+}
+
+func rangeLiteral(r xsd.VersionRange) string {
+	fields := []string{"Min: " + versionLiteral(r.Min)}
+	if r.Max != nil {
+		fields = append(fields, "Max: "+versionLiteral(r.Max), "MaxExclusive: true")
+	}
+	return "xsd.VersionRange{" + strings.Join(fields, ", ") + "}"
+}
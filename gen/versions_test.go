@@ -0,0 +1,91 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeVersion writes the export schema of the version into the directory
+// and returns its path
+func writeVersion(t *testing.T, dir, version, content string) string {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	src := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" version="` + version + `">` + content + `</xs:schema>`
+	path := filepath.Join(dir, "export.xsd")
+	if err := os.WriteFile(path, []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGenerateVersions(t *testing.T) {
+	dir := t.TempDir()
+	old := writeVersion(t, filepath.Join(dir, "4.4"), "4.4", `<xs:element name="export"><xs:complexType><xs:sequence>
+		<xs:element name="id" type="xs:string"/>
+	</xs:sequence><xs:attribute name="schemeVersion" type="xs:string"/></xs:complexType></xs:element>`)
+	new := writeVersion(t, filepath.Join(dir, "6.4"), "6.4", `<xs:element name="export"><xs:complexType><xs:sequence>
+		<xs:element name="id" type="xs:string"/>
+		<xs:element name="note" type="xs:string" minOccurs="0"/>
+	</xs:sequence><xs:attribute name="schemeVersion" type="xs:string"/></xs:complexType></xs:element>
+	<xs:element name="notice"><xs:complexType><xs:sequence>
+		<xs:element name="number" type="xs:string"/>
+	</xs:sequence><xs:attribute name="schemeVersion" type="xs:string"/></xs:complexType></xs:element>`)
+
+	schemas, err := Load([]string{new, old}, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, importPath := generatedDir(t)
+	opts := DefaultOptions()
+	opts.ImportPath = importPath
+	files, err := GenerateVersions(schemas, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const main = `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	for _, doc := range []string{
+		"<export schemeVersion=\"4.4\"><id>1</id></export>",
+		"<export schemeVersion=\"5.0\"><id>2</id></export>",
+		"<export schemeVersion=\"6.4\"><id>3</id><note>new</note></export>",
+		"<export><id>4</id></export>",
+		"<notice schemeVersion=\"6.4\"><number>5</number></notice>",
+		"<notice schemeVersion=\"4.4\"><number>6</number></notice>",
+	} {
+		v, err := Decode(strings.NewReader(doc))
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("%T %+v\n", v, v)
+	}
+}
+`
+	got := strings.Split(strings.TrimSpace(runGenerated(t, out, files, main)), "\n")
+	want := []string{
+		"*v4_4.Export &{SchemeVersion:4.4 ID:1}",
+		"*v4_4.Export &{SchemeVersion:5.0 ID:2}",
+		"*v6_4.Export &{SchemeVersion:6.4 ID:3 Note:new}",
+		"*v6_4.Export &{SchemeVersion: ID:4 Note:}",
+		"*v6_4.Notice &{SchemeVersion:6.4 Number:5}",
+		"no notice element of schema version 4.4",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("output is %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("decoded %s, want %s", got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/rpoletaev/parsexsd/gen"
+)
+
+const loggingUsage = `
+Common options:
+  -v                 Log debug messages
+  -log-format <fmt>  Format of logs, "text" or "json" for CI [default: text]
+`
+
+var (
+	verbose   bool
+	logFormat string
+
+	// warnings counts warnings logged since the last reset, they are
+	// reported by summaries
+	warnings = new(warningCounter)
+)
+
+// setupLogging applies logging options of the command
+func setupLogging() error {
+	switch logFormat {
+	case "", "text":
+		log.SetFormatter(&log.TextFormatter{})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unknown log format %q, it is text or json", logFormat)
+	}
+
+	log.SetLevel(log.InfoLevel)
+	if verbose {
+		log.SetLevel(log.DebugLevel)
+	}
+	log.AddHook(warnings)
+	return nil
+}
+
+// warningCounter is the hook counting warnings
+type warningCounter struct {
+	n int64
+}
+
+func (c *warningCounter) Levels() []log.Level {
+	return []log.Level{log.WarnLevel}
+}
+
+func (c *warningCounter) Fire(*log.Entry) error {
+	atomic.AddInt64(&c.n, 1)
+	return nil
+}
+
+func (c *warningCounter) reset() {
+	atomic.StoreInt64(&c.n, 0)
+}
+
+func (c *warningCounter) count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// logError logs the error of a command, errors of the compiler are logged
+// one by one with their positions and XSD components
+func logError(err error) {
+	var buildErr *gen.BuildError
+	if !errors.As(err, &buildErr) || len(buildErr.Diagnostics) == 0 {
+		log.Errorln(err)
+		return
+	}
+
+	for _, d := range buildErr.Diagnostics {
+		log.WithFields(log.Fields{
+			"file":      d.File,
+			"line":      d.Line,
+			"column":    d.Column,
+			"component": d.Component,
+		}).Error(d.Message)
+	}
+	log.Errorf("Plugin build failed: %v", buildErr.Err)
+}
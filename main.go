@@ -1,139 +1,178 @@
 package main
 
 import (
-	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
-	"path"
 	"path/filepath"
-
-	log "github.com/Sirupsen/logrus"
-
-	"golang.org/x/text/encoding/charmap"
-
 	"strings"
 
+	"github.com/rpoletaev/parsexsd/gen"
 	"github.com/rpoletaev/parsexsd/xsd"
 )
 
 var (
-	parsedFiles = make(map[string]struct{})
+	usage = `Usage: parsexsd <command> [options] <args>
 
-	repository, pckg, prefix string
-	exported                 bool
+Commands:
+  generate        Generate Go structs from an XSD schema
+  validate        Validate an XML document against an XSD schema
+  inspect         Print types resolved from an XSD schema
+  diff            Print changes between two releases of schemas
+  schema-version  Print version of a schema bundle
+  build-plugin    Build a Go plugin from generated code
 
-	usage = `Usage: parsexsd [options] <xsd_file>
-
-Options:
-  -o <file>     Destination file [default: stdout]
-  -p <package>  Package name [default: main]
-  -e            Generate exported structs [default: true]
-  -x <prefix>   Struct name prefix [default: ""]
+Run 'parsexsd <command> -h' for options of the command. Without a command
+the arguments, starting with a flag or a schema, are passed to generate. All
+commands log debug messages with -v and JSON lines with -log-format json.
 
 parsexsd is a tool for generating XML decoding/encoding Go structs, according
 to an XSD schema.
 `
+
+	// errUsage is returned by commands on wrong arguments, usage is
+	// already printed
+	errUsage = errors.New("wrong usage")
 )
 
-func main() {
-	flag.StringVar(&repository, "o", "/go/src/bitbucket.org/losaped/goszakupki/repository", "Name of output file")
-	flag.StringVar(&pckg, "p", "main", "Name of the Go package")
-	flag.StringVar(&prefix, "x", "", "Name of the Go package")
-	flag.BoolVar(&exported, "e", true, "Generate exported structs")
-	flag.Parse()
-
-	xsdFile := flag.Args()[0] //"/home/roma/Загрузки/zakupki/scheme4.4/fcsExport.xsd"
-	fdir, _ := filepath.Split(xsdFile)
-	version, err := xsd.GetSchemaVersion(filepath.Join(fdir, "IntegrationTypes.xsd"))
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Version is: ", version)
-	pluginDir := path.Join(repository, version.String())
-	if _, err = os.Stat(pluginDir); err != nil {
-		log.Println("Plugin directory is not exsist: ", err)
-		if err = os.Mkdir(pluginDir, 0777); err != nil {
-			log.Println("Error on create plugin dir: ", err)
-		}
-	}
+// command is a subcommand of parsexsd
+type command struct {
+	name string
+	run  func(args []string) error
+}
 
-	output := path.Join(pluginDir, "plugin.go")
-	log.Println(output)
-	out := os.Stdout
+var commands = []command{
+	{"generate", runGenerate},
+	{"validate", runValidate},
+	{"inspect", runInspect},
+	{"diff", runDiff},
+	{"schema-version", runSchemaVersion},
+	{"build-plugin", runBuildPlugin},
+}
 
-	if out, err = os.Create(output); err != nil {
-		log.Errorln("Could not create or truncate output file:", output)
-		os.Exit(1)
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
-	defer out.Close()
 
-	s, err := parseXSDFile(xsdFile)
-	if err != nil {
-		log.Fatal(err)
+	switch os.Args[1] {
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return
 	}
 
-	bldr := xsd.NewBuilder(s)
-
-	gen := generator{
-		pkg:      pckg,
-		prefix:   prefix,
-		exported: exported,
+	var run func(args []string) error
+	args := os.Args[2:]
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			run = c.run
+			break
+		}
+	}
+	if run == nil {
+		if !generateArg(os.Args[1]) {
+			fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+			os.Exit(2)
+		}
+		run, args = runGenerate, os.Args[1:]
 	}
 
-	if err := gen.do(out, bldr.BuildXML()); err != nil {
-		log.Errorln("Code generation failed unexpectedly:", err.Error())
+	switch err := run(args); {
+	case err == nil, err == flag.ErrHelp:
+	case err == errUsage:
+		os.Exit(2)
+	default:
+		logError(err)
 		os.Exit(1)
 	}
+}
 
-	compiler := NewPluginCompiler(path.Join(pluginDir, "export"), output)
-	if err = compiler.BuildPlugin(); err != nil {
-		println(err.Error())
-		log.Fatal(err)
+// generateArg reports whether the first argument, which is not a command,
+// is an argument of generate: a flag or a schema, so misspelled commands
+// are not taken for schemas
+func generateArg(arg string) bool {
+	if strings.HasPrefix(arg, "-") || strings.Contains(arg, "#") || strings.Contains(arg, "://") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(arg)) {
+	case ".xsd", ".zip":
+		return true
 	}
+	_, err := os.Stat(arg)
+	return err == nil
 }
 
-func makeCharsetReader(charset string, input io.Reader) (io.Reader, error) {
-	lc := strings.ToLower(charset)
-	if lc == "windows-1252" {
-		return charmap.Windows1252.NewDecoder().Reader(input), nil
+// newFlagSet returns flag set of a command printing the given usage, it
+// has logging options common for all commands
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage+loggingUsage)
 	}
-	if lc == "windows-1251" {
-		return charmap.Windows1251.NewDecoder().Reader(input), nil
+	fs.BoolVar(&verbose, "v", false, "Log debug messages")
+	fs.StringVar(&logFormat, "log-format", "text", "Format of logs, text or json")
+	return fs
+}
+
+// parseArgs parses flags of a command and checks the number of positional
+// arguments, negative nargs allows any number of them. Flags may follow
+// positional arguments, like validate doc.xml -schema a.xsd, arguments
+// after -- are positional.
+func parseArgs(fs *flag.FlagSet, args []string, nargs int) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if err == flag.ErrHelp {
+				return err
+			}
+			return errUsage
+		}
+		rest := fs.Args()
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
 	}
+	// flags are set, positional arguments are left for fs.Args
+	fs.Parse(append([]string{"--"}, positional...))
 
-	return nil, fmt.Errorf("Unsuported charset: '%s'", charset)
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	if nargs >= 0 && fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
 }
 
-func parseXSDFile(fname string) ([]xsd.Schema, error) {
-	f, err := os.Open(fname)
+// loadSchema reads a single entry point schema with all schemas it imports
+func loadSchema(name string, catalogs []string) ([]xsd.Schema, error) {
+	schemas, err := gen.Load([]string{name}, gen.LoadOptions{Catalogs: catalogs})
 	if err != nil {
-		return nil, fmt.Errorf("Не удалось открыть файл: %s\n%v", fname, err)
-	}
-	defer f.Close()
-
-	var schema xsd.Schema
-	d := xml.NewDecoder(f)
-	d.CharsetReader = makeCharsetReader
-	if err := d.Decode(&schema); err != nil {
-		log.Println("Не удалось декодировать схему, ", fname)
 		return nil, err
 	}
+	if len(schemas) != 1 {
+		return nil, fmt.Errorf("%s has %d root schemas, choose one of them with #name", name, len(schemas))
+	}
+	return schemas[0].Schemas, nil
+}
 
-	schemas := []xsd.Schema{schema}
-	dir, file := filepath.Split(fname)
-	parsedFiles[file] = struct{}{}
+// listFlag collects values of repeated flags, like -catalog
+type listFlag []string
 
-	for _, imp := range schema.Imports {
-		if _, ok := parsedFiles[imp.Location]; ok {
-			continue
-		}
-		s, err := parseXSDFile(filepath.Join(dir, imp.Location))
-		if err != nil {
-			return nil, err
-		}
-		schemas = append(schemas, s...)
-	}
-	return schemas, nil
+func (c *listFlag) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *listFlag) Set(value string) error {
+	*c = append(*c, value)
+	return nil
 }
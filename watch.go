@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/rpoletaev/parsexsd/gen"
+)
+
+// watchDelay is the time without changes of files before regeneration, so
+// saving several files regenerates once
+const watchDelay = 300 * time.Millisecond
+
+// watch generates code and regenerates it whenever files it is generated
+// from change. Directories of the files are watched, since editors replace
+// files on save. Errors are logged and watching goes on.
+func watch(cfg config, force bool) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	ws := watchSet{watcher: w, dirs: make(map[string]bool)}
+	run := func() {
+		inputs, err := generate(cfg, false, force)
+		if err != nil {
+			log.Errorf("Generation failed: %v", err)
+		}
+		// inputs of failed loads are unknown, inputs of the last load are
+		// watched until schemas load again
+		if inputs != nil || err == nil {
+			ws.inputs = inputs
+		}
+		ws.update(cfg)
+		log.Infof("Watching %d files for changes", len(ws.files))
+	}
+	run()
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case e, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 && ws.affects(e.Name) {
+				log.Debugf("%s changed", e.Name)
+				timer = time.After(watchDelay)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			log.Errorf("Watching failed: %v", err)
+		case <-timer:
+			timer = nil
+			run()
+		}
+	}
+}
+
+// watchSet is files and directory bundles generation reads
+type watchSet struct {
+	watcher *fsnotify.Watcher
+	// inputs are files read by the last generation which loaded schemas
+	inputs []gen.Source
+	files  map[string]bool
+	// dirs are watched directories, bundles are watched for new schemas
+	dirs    map[string]bool
+	bundles []string
+}
+
+// update watches inputs along with schemas and catalogs of the config,
+// which are watched even if they are missing. The set is built anew, so
+// files which are not read anymore, like schemas no longer imported, are
+// not watched.
+func (ws *watchSet) update(cfg config) {
+	names := append([]string(nil), cfg.Catalogs...)
+	for _, in := range ws.inputs {
+		// directory bundles are watched for new schemas below
+		if fi, err := os.Stat(in.Path); err == nil && fi.IsDir() {
+			continue
+		}
+		names = append(names, in.Path)
+	}
+	dirs := make(map[string]bool)
+	ws.bundles = nil
+	for _, s := range cfg.Schemas {
+		// entry points of archives are watched by their archives
+		if i := strings.LastIndex(s, "#"); i >= 0 {
+			s = s[:i]
+		}
+		if fi, err := os.Stat(s); err == nil && fi.IsDir() {
+			if abs, err := filepath.Abs(s); err == nil {
+				ws.bundles = append(ws.bundles, abs+string(filepath.Separator))
+			}
+			treeDirs(s, dirs)
+			continue
+		}
+		names = append(names, s)
+	}
+
+	ws.files = make(map[string]bool)
+	for _, name := range names {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			continue
+		}
+		ws.files[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+	ws.watchDirs(dirs)
+}
+
+// treeDirs adds the directory bundle with its subdirectories to dirs
+func treeDirs(root string, dirs map[string]bool) {
+	filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			if abs, err := filepath.Abs(p); err == nil {
+				dirs[abs] = true
+			}
+		}
+		return nil
+	})
+}
+
+// watchDirs watches the directories and stops watching others
+func (ws *watchSet) watchDirs(dirs map[string]bool) {
+	for dir := range ws.dirs {
+		if !dirs[dir] {
+			ws.watcher.Remove(dir)
+			delete(ws.dirs, dir)
+		}
+	}
+	for dir := range dirs {
+		if ws.dirs[dir] {
+			continue
+		}
+		if err := ws.watcher.Add(dir); err != nil {
+			log.Warnf("Could not watch %s: %v", dir, err)
+			continue
+		}
+		ws.dirs[dir] = true
+	}
+}
+
+// affects reports whether the change of the file affects generation
+func (ws *watchSet) affects(name string) bool {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return false
+	}
+	if ws.files[abs] {
+		return true
+	}
+	if !strings.EqualFold(filepath.Ext(abs), ".xsd") {
+		return false
+	}
+	for _, b := range ws.bundles {
+		if strings.HasPrefix(abs, b) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/rpoletaev/parsexsd/gen"
+)
+
+func TestWatchSet(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle")
+	if err := os.MkdirAll(filepath.Join(bundle, "common"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	path := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	for _, name := range []string{"export.xsd", "types.xsd", "old.xsd", "bundle/a.xsd", "bundle/common/b.xsd"} {
+		if err := os.WriteFile(path(name), []byte("<schema/>"), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ws := watchSet{watcher: w, dirs: make(map[string]bool)}
+	cfg := defaultConfig()
+	cfg.Schemas = []string{path("export.xsd"), bundle}
+	cfg.Catalogs = []string{path("catalogs/catalog.xml")}
+
+	ws.inputs = []gen.Source{{Path: path("export.xsd")}, {Path: path("types.xsd")}, {Path: path("old.xsd")}, {Path: bundle}}
+	ws.update(cfg)
+	tests := []struct {
+		name    string
+		affects bool
+	}{
+		{"export.xsd", true},
+		{"types.xsd", true},
+		{"old.xsd", true},
+		{"other.xsd", false},
+		{"catalogs/catalog.xml", true},
+		{"bundle/c.xsd", true},
+		{"bundle/common/d.XSD", true},
+		{"bundle/readme.txt", false},
+		{"bundle", false},
+	}
+	for _, tt := range tests {
+		if got := ws.affects(path(tt.name)); got != tt.affects {
+			t.Errorf("affects(%s) = %v, want %v", tt.name, got, tt.affects)
+		}
+	}
+	for _, d := range []string{dir, bundle, filepath.Join(bundle, "common")} {
+		if !ws.dirs[d] {
+			t.Errorf("%s is not watched", d)
+		}
+	}
+
+	// old.xsd is not imported anymore
+	ws.inputs = []gen.Source{{Path: path("export.xsd")}, {Path: path("types.xsd")}}
+	ws.update(cfg)
+	if ws.affects(path("old.xsd")) {
+		t.Error("old.xsd affects generation after it is not read")
+	}
+	if !ws.affects(path("types.xsd")) {
+		t.Error("types.xsd does not affect generation")
+	}
+
+	// the bundle is not generated anymore
+	cfg.Schemas = cfg.Schemas[:1]
+	ws.update(cfg)
+	if ws.affects(path("bundle/c.xsd")) {
+		t.Error("schemas of the removed bundle affect generation")
+	}
+	if ws.dirs[bundle] || ws.dirs[filepath.Join(bundle, "common")] {
+		t.Error("directories of the removed bundle are watched")
+	}
+}
@@ -23,5 +23,9 @@ func (all All) GetAllElements() []Element {
 			elements = append(elements, e.GetAllElements()...)
 		}
 	}
+
+	if all.Min == "0" {
+		return optional(elements)
+	}
 	return elements
 }
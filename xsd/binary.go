@@ -0,0 +1,226 @@
+package xsd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Base64Binary represents xs:base64Binary value
+// https://www.w3.org/TR/xmlschema11-2/#base64Binary
+type Base64Binary []byte
+
+// MarshalText implements encoding.TextMarshaler
+func (b Base64Binary) MarshalText() ([]byte, error) {
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(buf, b)
+	return buf, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Whitespace between
+// the base64 characters is allowed by the lexical space and is dropped.
+func (b *Base64Binary) UnmarshalText(text []byte) error {
+	text = dropSpace(text)
+	buf := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(buf, text)
+	if err != nil {
+		return err
+	}
+	*b = buf[:n]
+	return nil
+}
+
+// HexBinary represents xs:hexBinary value
+// https://www.w3.org/TR/xmlschema11-2/#hexBinary
+type HexBinary []byte
+
+// MarshalText implements encoding.TextMarshaler
+func (h HexBinary) MarshalText() ([]byte, error) {
+	buf := make([]byte, hex.EncodedLen(len(h)))
+	hex.Encode(buf, h)
+	return []byte(strings.ToUpper(string(buf))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (h *HexBinary) UnmarshalText(text []byte) error {
+	text = dropSpace(text)
+	buf := make([]byte, hex.DecodedLen(len(text)))
+	n, err := hex.Decode(buf, text)
+	if err != nil {
+		return err
+	}
+	*h = buf[:n]
+	return nil
+}
+
+// StreamTempDir is the directory where Base64Stream keeps decoded content.
+// Empty value means os.TempDir().
+var StreamTempDir string
+
+// Base64Stream is a streaming variant of Base64Binary for large embedded
+// documents. On decoding the content is written to a temporary file (or to
+// Writer, if it was set before decoding) instead of being kept in memory.
+// The caller is responsible for calling Remove when the file is not needed.
+type Base64Stream struct {
+	// Path of the file with decoded content, empty if Writer was used
+	Path string
+	// Size of decoded content in bytes
+	Size int64
+	// Writer receives decoded content instead of a temporary file
+	Writer io.Writer
+}
+
+// NewBase64Stream returns Base64Stream which decodes content into w
+func NewBase64Stream(w io.Writer) *Base64Stream {
+	return &Base64Stream{Writer: w}
+}
+
+// UnmarshalXML implements xml.Unmarshaler. On failure the temporary file
+// is removed and Path is cleared.
+func (s *Base64Stream) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if s.Writer != nil {
+		return s.decode(d, s.Writer)
+	}
+
+	f, err := os.CreateTemp(StreamTempDir, "xsd-base64-*")
+	if err != nil {
+		return err
+	}
+	s.Path = f.Name()
+	err = s.decode(d, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		s.Remove()
+		s.Size = 0
+	}
+	return err
+}
+
+// decode writes decoded character data of the element into dst
+func (s *Base64Stream) decode(d *xml.Decoder, dst io.Writer) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		n, err := io.Copy(dst, base64.NewDecoder(base64.StdEncoding, spaceDropper{pr}))
+		s.Size = n
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			pw.CloseWithError(err)
+			<-done
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			if _, err := pw.Write(t); err != nil {
+				<-done
+				return err
+			}
+		case xml.EndElement:
+			pw.Close()
+			return <-done
+		case xml.StartElement:
+			if err := d.Skip(); err != nil {
+				pw.CloseWithError(err)
+				<-done
+				return err
+			}
+		}
+	}
+}
+
+// MarshalXML implements xml.Marshaler, content is read from Path and
+// encoded in chunks. Content decoded into Writer or removed is not kept, so
+// such a stream is not encoded, the zero stream is omitted like an absent
+// element.
+func (s Base64Stream) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if s.Path == "" {
+		if s.Writer != nil || s.Size > 0 {
+			return fmt.Errorf("content of %s is not kept, it is decoded into Writer or removed", start.Name.Local)
+		}
+		return nil
+	}
+
+	f, err := s.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	// chunk size is a multiple of 3 so chunks are encoded without padding
+	buf := make([]byte, 3*1024)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			if err := e.EncodeToken(xml.CharData(base64.StdEncoding.EncodeToString(buf[:n]))); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// Open opens decoded content for reading
+func (s Base64Stream) Open() (io.ReadCloser, error) {
+	return os.Open(s.Path)
+}
+
+// Remove deletes the temporary file with decoded content
+func (s *Base64Stream) Remove() error {
+	if s.Path == "" {
+		return nil
+	}
+	err := os.Remove(s.Path)
+	s.Path = ""
+	return err
+}
+
+// spaceDropper filters out whitespace of base64 lexical representation
+type spaceDropper struct {
+	r io.Reader
+}
+
+func (s spaceDropper) Read(p []byte) (int, error) {
+	for {
+		n, err := s.r.Read(p)
+		n = len(dropSpace(p[:n]))
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// dropSpace removes XML whitespace characters in place
+func dropSpace(b []byte) []byte {
+	res := b[:0]
+	for _, c := range b {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		res = append(res, c)
+	}
+	return res
+}
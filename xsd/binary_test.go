@@ -0,0 +1,189 @@
+package xsd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"testing"
+)
+
+type testBinary struct {
+	XMLName xml.Name     `xml:"doc"`
+	Base64  Base64Binary `xml:"base64"`
+	Hex     HexBinary    `xml:"hex"`
+}
+
+func TestBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		base64   string
+		hex      string
+		original string
+	}{
+		{
+			name:     "canonical",
+			doc:      "<doc><base64>aGVsbG8=</base64><hex>FF00</hex></doc>",
+			base64:   "hello",
+			hex:      "\xff\x00",
+			original: "<doc><base64>aGVsbG8=</base64><hex>FF00</hex></doc>",
+		},
+		{
+			name:     "line breaks",
+			doc:      "<doc><base64>\n  aGVs\r\n  bG8=\n</base64><hex> ff00\n</hex></doc>",
+			base64:   "hello",
+			hex:      "\xff\x00",
+			original: "<doc><base64>aGVsbG8=</base64><hex>FF00</hex></doc>",
+		},
+		{
+			name:     "empty",
+			doc:      "<doc><base64></base64><hex></hex></doc>",
+			original: "<doc><base64></base64><hex></hex></doc>",
+		},
+	}
+	for _, tt := range tests {
+		var v testBinary
+		if err := xml.Unmarshal([]byte(tt.doc), &v); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(v.Base64) != tt.base64 || string(v.Hex) != tt.hex {
+			t.Errorf("%s: decoded %q and %q, want %q and %q", tt.name, v.Base64, v.Hex, tt.base64, tt.hex)
+		}
+		data, err := xml.Marshal(v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(data) != tt.original {
+			t.Errorf("%s: encoded %s, want %s", tt.name, data, tt.original)
+		}
+	}
+
+	for _, doc := range []string{
+		"<doc><base64>aGVsbG8</base64></doc>",
+		"<doc><hex>F</hex></doc>",
+		"<doc><hex>GG</hex></doc>",
+	} {
+		var v testBinary
+		if err := xml.Unmarshal([]byte(doc), &v); err == nil {
+			t.Errorf("%s is decoded without error", doc)
+		}
+	}
+}
+
+type testStream struct {
+	XMLName xml.Name     `xml:"doc"`
+	Data    Base64Stream `xml:"data,omitempty"`
+}
+
+// tempFiles returns names of files in the directory
+func tempFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestBase64StreamFile(t *testing.T) {
+	defer func(dir string) { StreamTempDir = dir }(StreamTempDir)
+	StreamTempDir = t.TempDir()
+
+	// content of several chunks of MarshalXML, encoded with line breaks
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	text, _ := Base64Binary(content).MarshalText()
+	doc := []byte("<doc><data>")
+	for len(text) > 76 {
+		doc = append(append(doc, text[:76]...), "\r\n"...)
+		text = text[76:]
+	}
+	doc = append(append(doc, text...), "</data></doc>"...)
+
+	var v testStream
+	if err := xml.Unmarshal(doc, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Data.Path == "" || v.Data.Size != int64(len(content)) {
+		t.Fatalf("decoded into %q of %d bytes, want a file of %d bytes", v.Data.Path, v.Data.Size, len(content))
+	}
+	f, err := v.Data.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Error("decoded content differs")
+	}
+
+	data, err := xml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again testBinary
+	if err := xml.Unmarshal(bytes.Replace(data, []byte("data>"), []byte("base64>"), 2), &again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Base64, content) {
+		t.Error("encoded content differs")
+	}
+
+	if err := v.Data.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if files := tempFiles(t, StreamTempDir); len(files) > 0 {
+		t.Errorf("files %v are left after Remove", files)
+	}
+	if _, err := xml.Marshal(v); err == nil {
+		t.Error("removed stream is encoded without error")
+	}
+}
+
+func TestBase64StreamWriter(t *testing.T) {
+	var buf bytes.Buffer
+	v := testStream{Data: *NewBase64Stream(&buf)}
+	if err := xml.Unmarshal([]byte("<doc><data>aGVs\nbG8=</data></doc>"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "hello" || v.Data.Size != 5 || v.Data.Path != "" {
+		t.Errorf("decoded %q of %d bytes into %q, want hello into the writer", buf.String(), v.Data.Size, v.Data.Path)
+	}
+	if _, err := xml.Marshal(v); err == nil {
+		t.Error("stream decoded into the writer is encoded without error")
+	}
+}
+
+func TestBase64StreamError(t *testing.T) {
+	defer func(dir string) { StreamTempDir = dir }(StreamTempDir)
+	StreamTempDir = t.TempDir()
+
+	var v testStream
+	if err := xml.Unmarshal([]byte("<doc><data>aGVs*bG8=</data></doc>"), &v); err == nil {
+		t.Fatal("invalid content is decoded without error")
+	}
+	if v.Data.Path != "" || v.Data.Size != 0 {
+		t.Errorf("failed stream has path %q and size %d", v.Data.Path, v.Data.Size)
+	}
+	if files := tempFiles(t, StreamTempDir); len(files) > 0 {
+		t.Errorf("files %v are left after decoding error", files)
+	}
+}
+
+func TestBase64StreamZero(t *testing.T) {
+	data, err := xml.Marshal(testStream{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "<doc></doc>" {
+		t.Errorf("zero stream is encoded as %s, want <doc></doc>", data)
+	}
+}
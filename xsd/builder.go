@@ -1,72 +1,225 @@
 package xsd
 
-import "strings"
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
 
 type builder struct {
-	schemas    []Schema
-	complTypes map[string]ComplexType
-	simplTypes map[string]SimpleType
+	schemas       []Schema
+	complTypes    map[string]ComplexType
+	simplTypes    map[string]SimpleType
+	complOrder    []string
+	complSchemas  map[string]int
+	simplSchemas  map[string]int
+	typeOverrides map[string]string
+	streamBinary  bool
+	nillable      bool
+
+	// warn receives constructs which are skipped, each of them once
+	warn   func(Warning)
+	warned map[string]bool
+	// schema and component are the top-level component being built
+	schema    int
+	component string
+	// scope is the schema resolving prefixes of type references, it is the
+	// schema of the named type being built
+	scope int
+}
+
+// Warning is a construct of a schema which is not supported and is skipped
+// or approximated by generated code
+type Warning struct {
+	// Location is the URI of the schema, Line is the line of the top-level
+	// component the construct belongs to, 0 if it is not known
+	Location string
+	Line     int
+	// Component is the top-level component, like complexType
+	// zfcs_contractType
+	Component string
+	Message   string
+}
+
+// File returns path of the schema, schemas of bundles are relative to
+// their bundles
+func (w Warning) File() string {
+	if u, err := url.Parse(w.Location); err == nil {
+		switch u.Scheme {
+		case "file":
+			return filepath.FromSlash(u.Path)
+		case "fs":
+			return strings.TrimPrefix(u.Path, "/")
+		}
+	}
+	return w.Location
+}
+
+// String returns the warning like "schema.xsd:12: complexType foo: message"
+func (w Warning) String() string {
+	position := w.File()
+	if w.Line > 0 {
+		position = fmt.Sprintf("%s:%d", position, w.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", position, w.Component, w.Message)
 }
 
 // NewBuilder creates a new initialized builder populated with the given
 // xsdSchema slice.
 func NewBuilder(schemas []Schema) *builder {
 	return &builder{
-		schemas:    schemas,
-		complTypes: make(map[string]ComplexType),
-		simplTypes: make(map[string]SimpleType),
+		schemas:      schemas,
+		complTypes:   make(map[string]ComplexType),
+		simplTypes:   make(map[string]SimpleType),
+		complSchemas: make(map[string]int),
+		simplSchemas: make(map[string]int),
+		nillable:     true,
+		warned:       make(map[string]bool),
 	}
 }
 
+// OnWarning sets the receiver of constructs which are not supported, they
+// are dropped by default
+func (b *builder) OnWarning(warn func(Warning)) {
+	b.warn = warn
+}
+
+// warnf reports the construct of the component being built
+func (b *builder) warnf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	key := b.component + " " + msg
+	if b.warn == nil || b.warned[key] {
+		return
+	}
+	b.warned[key] = true
+
+	w := Warning{Component: b.component, Message: msg}
+	if b.schema < len(b.schemas) {
+		w.Location = b.schemas[b.schema].Location
+		w.Line = b.schemas[b.schema].Lines[b.component]
+	}
+	b.warn(w)
+}
+
+// enter makes the top-level component of the schema current for warnings
+func (b *builder) enter(schema int, kind, name string) {
+	b.schema, b.component = schema, kind+" "+name
+	b.scope = schema
+}
+
+// within makes the schema the scope of type references until the returned
+// function is called
+func (b *builder) within(schema int) func() {
+	prev := b.scope
+	b.scope = schema
+	return func() { b.scope = prev }
+}
+
+// StreamBinary makes elements of xs:base64Binary type decode into
+// temporary files (xsd.Base64Stream) instead of memory.
+func (b *builder) StreamBinary(stream bool) {
+	b.streamBinary = stream
+}
+
+// Nillable makes nillable elements keep xsi:nil (xsd.Nillable), it is on
+// by default.
+func (b *builder) Nillable(nillable bool) {
+	b.nillable = nillable
+}
+
+// OverrideTypes sets Go types of XSD types by their names, which take
+// precedence over schema definitions and built-in mappings. Names are
+// qualified like {http://zakupki.gov.ru/oos/types/1}guidType, prefixes of
+// references are resolved by namespace declarations of their schemas, or
+// are local names matching types of any namespace. Types of other packages
+// are given with import path, e.g. github.com/google/uuid.UUID.
+func (b *builder) OverrideTypes(types map[string]string) {
+	b.typeOverrides = types
+}
+
 type XmlTree struct {
 	Name         string
 	Type         string
 	List         bool
 	Cdata        bool
-	Attribs      []xmlAttrib
+	Attribs      []XmlAttrib
 	Children     []*XmlTree
 	StructNeeded bool
+	Root         bool
+	Optional     bool
+	Nillable     bool
+	Facets       *Facets
+	Constraints  []IdentityConstraint
+	// Namespace and Location are the target namespace and location of the
+	// schema defining the root element or the named complex type of the
+	// tree, they are empty for inline types and simple values
+	Namespace string
+	Location  string
 }
 
-type xmlAttrib struct {
-	Name string
-	Type string
+type XmlAttrib struct {
+	Name     string
+	Type     string
+	Optional bool
+	Facets   *Facets
 }
 
 // buildXML generates and returns a tree of XmlTree objects based on a set of
 // parsed XSD schemas.
 func (b *builder) BuildXML() []*XmlTree {
 	var roots []Element
-	for _, s := range b.schemas {
+	var rootSchemas []int
+	for i, s := range b.schemas {
 		roots = append(roots, s.Elements...)
+		for range s.Elements {
+			rootSchemas = append(rootSchemas, i)
+		}
 		for _, t := range s.ComplexTypes {
-			if t.Name != "" {
-				b.complTypes[t.Name] = t
+			if t.Name == "" {
+				continue
 			}
+			if _, ok := b.complTypes[t.Name]; !ok {
+				b.complOrder = append(b.complOrder, t.Name)
+			}
+			b.complTypes[t.Name] = t
+			b.complSchemas[t.Name] = i
 		}
 		for _, t := range s.SimpleTypes {
 			b.simplTypes[t.Name] = t
+			b.simplSchemas[t.Name] = i
 		}
 	}
 
 	var xelems []*XmlTree
-	for _, e := range roots {
-		xelems = append(xelems, b.BuildFromElement(e))
+	for i, e := range roots {
+		b.enter(rootSchemas[i], "element", e.Name)
+		xelem := b.BuildFromElement(e)
+		xelem.Root = true
+		b.setSchema(xelem, rootSchemas[i])
+		xelems = append(xelems, xelem)
 	}
 
-	for _, t := range b.complTypes {
-		if t.Name != "" {
-			xelem := &XmlTree{
-				Name:         t.Name,
-				StructNeeded: true,
-			}
-			b.BuildFromComplexType(xelem, t)
-			xelems = append(xelems, xelem)
+	// types go in order of schemas, so output is the same between runs
+	for _, name := range b.complOrder {
+		xelem := &XmlTree{
+			Name:         name,
+			StructNeeded: true,
 		}
+		b.enter(b.complSchemas[name], "complexType", name)
+		b.BuildFromComplexType(xelem, b.complTypes[name])
+		b.setSchema(xelem, b.complSchemas[name])
+		xelems = append(xelems, xelem)
 	}
 	return xelems
 }
 
+// setSchema sets namespace and location of the schema with the index
+func (b *builder) setSchema(xelem *XmlTree, i int) {
+	xelem.Namespace = b.schemas[i].TargetNamespace
+	xelem.Location = b.schemas[i].Location
+}
+
 // buildFromElement builds an XmlTree from an xsdElement, recursively
 // traversing the XSD type information to build up an XML element hierarchy.
 func (b *builder) BuildFromElement(e Element) *XmlTree {
@@ -80,15 +233,29 @@ func (b *builder) BuildFromElement(e Element) *XmlTree {
 		xelem.List = true
 	}
 
+	if e.Min == "0" {
+		xelem.Optional = true
+	}
+
+	if e.Nillable && b.nillable {
+		xelem.Nillable = true
+	}
+
+	xelem.Constraints = e.IdentityConstraints()
+
 	if !e.IsInlineType() {
 		xelem.StructNeeded = false
 		switch t := b.findType(e.Type).(type) {
 		case ComplexType:
 			xelem.Type = t.Name
+			b.setSchema(xelem, b.complSchemas[t.Name])
 		case SimpleType:
 			b.BuildFromSimpleType(xelem, t)
 		case string:
 			xelem.Type = t
+			if b.streamBinary && t == "xsd.Base64Binary" {
+				xelem.Type = "xsd.Base64Stream"
+			}
 		}
 		return xelem
 	}
@@ -109,6 +276,10 @@ func (b *builder) BuildFromElement(e Element) *XmlTree {
 // buildFromComplexType takes an XmlTree and an xsdComplexType, containing
 // XSD type information for XmlTree enrichment.
 func (b *builder) BuildFromComplexType(xelem *XmlTree, t ComplexType) {
+	if i, ok := b.complSchemas[t.Name]; ok && t.Name != "" {
+		defer b.within(i)()
+	}
+
 	if t.Sequence != nil { // Does the element have children?
 		for _, e := range t.Sequence.GetAllElements() {
 			xelem.Children = append(xelem.Children, b.BuildFromElement(e))
@@ -147,22 +318,70 @@ func (b *builder) BuildFromComplexType(xelem *XmlTree, t ComplexType) {
 }
 
 // buildFromSimpleType assumes restriction child and fetches the base value,
-// assuming that value is of a XSD built-in data type.
+// assuming that value is of a XSD built-in data type. Facets of the whole
+// restriction chain are collected into xelem.Facets.
 func (b *builder) BuildFromSimpleType(xelem *XmlTree, t SimpleType) {
+	var facets Facets
+	b.buildFromSimpleType(xelem, t, &facets)
+	xelem.Facets = whiteSpaceType(xelem, facets)
+}
+
+func (b *builder) buildFromSimpleType(xelem *XmlTree, t SimpleType, facets *Facets) {
+	if t.Restriction.Base == "" {
+		b.warnf("simple type %s is a list, a union or a restriction of an inline type, which are not supported, it is decoded as string", t.Name)
+		xelem.Type = "string"
+		return
+	}
+	if i, ok := b.simplSchemas[t.Name]; ok && t.Name != "" {
+		defer b.within(i)()
+	}
+	facets.Inherit(t.Restriction)
 	switch tp := b.findType(t.Restriction.Base).(type) {
 	case string:
 		xelem.Type = tp
 	case SimpleType:
-		b.BuildFromSimpleType(xelem, tp)
+		b.buildFromSimpleType(xelem, tp, facets)
 	case ComplexType:
 		b.BuildFromComplexType(xelem, tp)
 	}
 }
 
+// whiteSpaceType replaces string type of the element with a normalizing one
+// according to the whiteSpace facet and returns facets left to check, or nil
+// if there are none.
+func whiteSpaceType(xelem *XmlTree, facets Facets) *Facets {
+	switch xelem.Type {
+	case "string":
+		switch facets.WhiteSpace {
+		case WhiteSpaceReplace:
+			xelem.Type = "xsd.NormalizedString"
+		case WhiteSpaceCollapse:
+			xelem.Type = "xsd.Token"
+		}
+	case "xsd.NormalizedString":
+		if facets.WhiteSpace == "" {
+			facets.WhiteSpace = WhiteSpaceReplace
+		}
+	case "xsd.Token":
+		if facets.WhiteSpace == "" {
+			facets.WhiteSpace = WhiteSpaceCollapse
+		}
+	}
+
+	// the value type normalizes itself, whiteSpace alone is nothing to check
+	if facets.IsEmpty() {
+		return nil
+	}
+	return &facets
+}
+
 func (b *builder) BuildFromComplexContent(xelem *XmlTree, c ComplexContent) {
 	if c.Extension != nil {
 		b.BuildFromExtension(xelem, c.Extension)
 	}
+	if c.Restriction != nil {
+		b.warnf("restriction of complex content %s is not supported, it is skipped", c.Restriction.Base)
+	}
 }
 
 // A simple content can refer to a text-only complex type
@@ -215,25 +434,31 @@ func (b *builder) BuildFromRestriction(xelem *XmlTree, r *Restriction) {
 		b.BuildFromSimpleType(xelem, t)
 	case ComplexType:
 		b.BuildFromComplexType(xelem, t)
-	case ComplexContent:
-		panic("Restriction on complex content is not implemented")
+	case string:
+		xelem.Type = t
 	default:
-		panic("Unexpected base type to restriction")
+		b.warnf("restriction of simple content with base %s is not supported, it is skipped", r.Base)
 	}
 }
 
 func (b *builder) BuildFromAttributes(xelem *XmlTree, attrs []Attribute) {
 	for _, a := range attrs {
-		attr := xmlAttrib{Name: a.Name}
+		attr := XmlAttrib{Name: a.Name, Optional: a.Use != "required"}
 		switch t := b.findType(a.Type).(type) {
 		case SimpleType:
-			// Get type name from simpleType
-			// If Restriction.Base is a simpleType or complexType, we panic
-			attr.Type = b.findType(t.Restriction.Base).(string)
+			// Get type name and facets from simpleType
+			value := &XmlTree{}
+			b.BuildFromSimpleType(value, t)
+			attr.Type = value.Type
+			attr.Facets = value.Facets
 		case string:
 			// If empty, then simpleType is present as content, but we ignore
 			// that now
 			attr.Type = t
+			if a.Type == "" {
+				b.warnf("inline simple type of attribute %s is not supported, it is decoded as string", a.Name)
+				attr.Type = "string"
+			}
 		}
 		xelem.Attribs = append(xelem.Attribs, attr)
 	}
@@ -245,26 +470,43 @@ func (b *builder) BuildFromAttributes(xelem *XmlTree, attrs []Attribute) {
 // Go correspondents. If no XSD type was found, the type name itself is
 // returned.
 func (b *builder) findType(name string) interface{} {
+	if t, ok := b.typeOverrides[b.qualify(name)]; ok {
+		return t
+	}
 	name = stripNamespace(name)
+	if t, ok := b.typeOverrides[name]; ok {
+		return t
+	}
 	if t, ok := b.complTypes[name]; ok {
-		//println("has ComplexType with name ", name)
 		return t
 	}
 	if t, ok := b.simplTypes[name]; ok {
-		//println("has SimpleType with name ", name)
-		// fmt.Printf("%v#\n", t)
 		return t
 	}
 
 	switch name {
 	case "boolean":
 		return "bool"
-	case "language", "Name", "token", "duration", "anyURI":
+	case "duration", "anyURI", "string":
 		return "string"
+	case "normalizedString":
+		return "xsd.NormalizedString"
+	case "token", "language", "Name", "NCName", "NMTOKEN", "ID", "IDREF", "ENTITY":
+		return "xsd.Token"
 	case "long", "short", "integer", "int":
 		return "int64"
+	case "byte":
+		return "int8"
+	case "unsignedLong", "nonNegativeInteger":
+		return "uint64"
+	case "unsignedInt":
+		return "uint32"
 	case "unsignedShort":
 		return "uint16"
+	case "unsignedByte":
+		return "uint8"
+	case "float":
+		return "float32"
 	case "decimal", "double":
 		return "float64"
 	case "dateTime":
@@ -272,14 +514,33 @@ func (b *builder) findType(name string) interface{} {
 	case "date":
 		return "xsd.Date"
 	case "base64Binary":
-		return "[]byte"
+		return "xsd.Base64Binary"
+	case "hexBinary":
+		return "xsd.HexBinary"
 	case "positiveInteger":
 		return "uint64"
+	case "":
+		return name
 	default:
+		b.warnf("type %s is not defined in the schemas, it is used as Go type %s", name, name)
 		return name
 	}
 }
 
+// qualify returns the type reference like {namespace}name, resolving its
+// prefix in the schema of the scope
+func (b *builder) qualify(name string) string {
+	prefix, local := "", name
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		prefix, local = name[:i], name[i+1:]
+	}
+	var ns string
+	if b.scope < len(b.schemas) {
+		ns = b.schemas[b.scope].Namespace(prefix)
+	}
+	return "{" + ns + "}" + local
+}
+
 func stripNamespace(name string) string {
 	if s := strings.Split(name, ":"); len(s) > 1 {
 		return s[len(s)-1]
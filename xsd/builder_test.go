@@ -0,0 +1,51 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestCompositorsOptional(t *testing.T) {
+	const src = `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+	<xs:element name="doc"><xs:complexType><xs:sequence>
+		<xs:element name="id" type="xs:string"/>
+		<xs:choice>
+			<xs:element name="inn" type="xs:string"/>
+			<xs:element name="kpp" type="xs:string"/>
+		</xs:choice>
+		<xs:sequence minOccurs="0">
+			<xs:element name="note" type="xs:string"/>
+		</xs:sequence>
+		<xs:sequence>
+			<xs:element name="date" type="xs:string"/>
+		</xs:sequence>
+	</xs:sequence></xs:complexType></xs:element>
+	<xs:element name="info"><xs:complexType><xs:all minOccurs="0">
+		<xs:element name="name" type="xs:string"/>
+	</xs:all></xs:complexType></xs:element>
+	</xs:schema>`
+	var s Schema
+	if err := xml.Unmarshal([]byte(src), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	optional := make(map[string]bool)
+	for _, root := range NewBuilder([]Schema{s}).BuildXML() {
+		for _, c := range root.Children {
+			optional[c.Name] = c.Optional
+		}
+	}
+	want := map[string]bool{
+		"id":   false,
+		"inn":  true,
+		"kpp":  true,
+		"note": true,
+		"date": false,
+		"name": true,
+	}
+	for name, opt := range want {
+		if got, ok := optional[name]; !ok || got != opt {
+			t.Errorf("%s is optional %v, want %v", name, got, opt)
+		}
+	}
+}
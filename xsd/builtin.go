@@ -2,10 +2,24 @@ package xsd
 
 import (
 	"encoding/xml"
+	"fmt"
 	"time"
 )
 
-// DefaultXSDDateFormat стандартныое представление типа xs:date
+// ParseBoolean parses xs:boolean value, which is one of true, false, 1 and
+// 0, surrounding whitespace is collapsed
+// https://www.w3.org/TR/xmlschema11-2/#boolean
+func ParseBoolean(value string) (bool, error) {
+	switch NormalizeWhiteSpace(value, WhiteSpaceCollapse) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", value)
+}
+
+// DefaultXSDDateFormat is the layout of xs:date values
 // https://www.w3.org/TR/xmlschema11-2/#date
 const DefaultXSDDateFormat = "2006-01-02"
 
@@ -19,7 +33,7 @@ type Date struct {
 	time.Time
 }
 
-// UnmarshalXMLAttr Позволяет правильно декодировать дату в формате "yyyy-mm-dd"
+// UnmarshalXMLAttr decodes the date attribute like "yyyy-mm-dd"
 func (c *Date) UnmarshalXMLAttr(attr xml.Attr) error {
 	t, err := time.ParseInLocation(time.RFC3339, attr.Value, defaultLocation)
 	if err != nil {
@@ -30,7 +44,7 @@ func (c *Date) UnmarshalXMLAttr(attr xml.Attr) error {
 	return nil
 }
 
-// UnmarshalXML Позволяет правильно декодировать дату в формате "yyyy-mm-dd"
+// UnmarshalXML decodes the date element like "yyyy-mm-dd"
 func (c *Date) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	var v string
 	d.DecodeElement(&v, &start)
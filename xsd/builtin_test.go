@@ -0,0 +1,27 @@
+package xsd
+
+import "testing"
+
+func TestParseBoolean(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+		ok    bool
+	}{
+		{"true", true, true},
+		{"1", true, true},
+		{"false", false, true},
+		{"0", false, true},
+		{" true\n", true, true},
+		{"TRUE", false, false},
+		{"T", false, false},
+		{"yes", false, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseBoolean(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseBoolean(%q) = %v, %v, want %v, ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
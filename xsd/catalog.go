@@ -0,0 +1,157 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// CatalogNamespace is the namespace of OASIS XML catalogs
+const CatalogNamespace = "urn:oasis:names:tc:entity:xmlns:xml:catalog"
+
+// Catalog maps URIs of schemas to their local copies, it is read from OASIS
+// XML catalog files. uri and system entries map the whole URI, rewriteURI
+// and rewriteSystem entries map URIs by prefix, the longest prefix wins.
+// https://www.oasis-open.org/committees/download.php/14809/xml-catalogs.html
+type Catalog struct {
+	exact    map[string]string
+	rewrites []catalogRewrite
+	next     []string
+}
+
+type catalogRewrite struct {
+	prefix  string
+	replace string
+}
+
+// catalogEntries are the entries of a catalog or a group of it
+type catalogEntries struct {
+	URIs []struct {
+		Name string `xml:"name,attr"`
+		URI  string `xml:"uri,attr"`
+	} `xml:"uri"`
+	Systems []struct {
+		SystemID string `xml:"systemId,attr"`
+		URI      string `xml:"uri,attr"`
+	} `xml:"system"`
+	RewriteURIs []struct {
+		Start  string `xml:"uriStartString,attr"`
+		Prefix string `xml:"rewritePrefix,attr"`
+	} `xml:"rewriteURI"`
+	RewriteSystems []struct {
+		Start  string `xml:"systemIdStartString,attr"`
+		Prefix string `xml:"rewritePrefix,attr"`
+	} `xml:"rewriteSystem"`
+	NextCatalogs []struct {
+		Catalog string `xml:"catalog,attr"`
+	} `xml:"nextCatalog"`
+	Groups []catalogEntries `xml:"group"`
+}
+
+// NewCatalog returns an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{exact: make(map[string]string)}
+}
+
+// ParseCatalog reads a catalog file located at base, relative URIs of its
+// entries are resolved against base
+func ParseCatalog(r io.Reader, base string) (*Catalog, error) {
+	var entries catalogEntries
+	if err := xml.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCatalog()
+	if err := c.add(entries, baseURL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Catalog) add(entries catalogEntries, base *url.URL) error {
+	resolve := func(ref string) (string, error) {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return "", err
+		}
+		return base.ResolveReference(u).String(), nil
+	}
+
+	for _, e := range entries.URIs {
+		uri, err := resolve(e.URI)
+		if err != nil {
+			return err
+		}
+		c.exact[e.Name] = uri
+	}
+	for _, e := range entries.Systems {
+		uri, err := resolve(e.URI)
+		if err != nil {
+			return err
+		}
+		c.exact[e.SystemID] = uri
+	}
+	for _, e := range entries.RewriteURIs {
+		prefix, err := resolve(e.Prefix)
+		if err != nil {
+			return err
+		}
+		c.rewrites = append(c.rewrites, catalogRewrite{e.Start, prefix})
+	}
+	for _, e := range entries.RewriteSystems {
+		prefix, err := resolve(e.Prefix)
+		if err != nil {
+			return err
+		}
+		c.rewrites = append(c.rewrites, catalogRewrite{e.Start, prefix})
+	}
+	for _, e := range entries.NextCatalogs {
+		next, err := resolve(e.Catalog)
+		if err != nil {
+			return err
+		}
+		c.next = append(c.next, next)
+	}
+	for _, g := range entries.Groups {
+		if err := c.add(g, base); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Merge adds entries of another catalog, entries of c take precedence
+func (c *Catalog) Merge(other *Catalog) {
+	for name, uri := range other.exact {
+		if _, ok := c.exact[name]; !ok {
+			c.exact[name] = uri
+		}
+	}
+	c.rewrites = append(c.rewrites, other.rewrites...)
+}
+
+// Resolve returns the local URI of the given URI or namespace
+func (c *Catalog) Resolve(uri string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if local, ok := c.exact[uri]; ok {
+		return local, true
+	}
+
+	var best *catalogRewrite
+	for i, r := range c.rewrites {
+		if strings.HasPrefix(uri, r.prefix) && (best == nil || len(r.prefix) > len(best.prefix)) {
+			best = &c.rewrites[i]
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.replace + strings.TrimPrefix(uri, best.prefix), true
+}
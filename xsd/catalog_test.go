@@ -0,0 +1,74 @@
+package xsd
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCatalogResolve(t *testing.T) {
+	const catalog = `<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+		<uri name="urn:types" uri="types.xsd"/>
+		<system systemId="http://example.com/common.xsd" uri="/schemas/common.xsd"/>
+		<rewriteURI uriStartString="http://example.com/" rewritePrefix="mirror/"/>
+		<group>
+			<rewriteSystem systemIdStartString="http://example.com/oos/" rewritePrefix="file:///oos/"/>
+		</group>
+	</catalog>`
+	c, err := ParseCatalog(strings.NewReader(catalog), "file:///project/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		uri   string
+		local string
+	}{
+		{"urn:types", "file:///project/types.xsd"},
+		{"http://example.com/common.xsd", "file:///schemas/common.xsd"},
+		{"http://example.com/a/b.xsd", "file:///project/mirror/a/b.xsd"},
+		{"http://example.com/oos/export.xsd", "file:///oos/export.xsd"},
+		{"http://other.com/a.xsd", ""},
+	}
+	for _, tt := range tests {
+		local, ok := c.Resolve(tt.uri)
+		if local != tt.local || ok != (tt.local != "") {
+			t.Errorf("Resolve(%s) = %s, %v, want %s", tt.uri, local, ok, tt.local)
+		}
+	}
+}
+
+func TestLoaderCatalog(t *testing.T) {
+	fsys := fstest.MapFS{
+		"catalog.xml": {Data: []byte(`<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+			<rewriteURI uriStartString="http://example.com/" rewritePrefix="remote/"/>
+			<nextCatalog catalog="more/catalog.xml"/>
+		</catalog>`)},
+		"more/catalog.xml": {Data: []byte(`<catalog xmlns="urn:oasis:names:tc:entity:xmlns:xml:catalog">
+			<uri name="http://example.com/types.xsd" uri="../local/types.xsd"/>
+		</catalog>`)},
+		"export.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+			<xs:import namespace="urn:types" schemaLocation="http://example.com/types.xsd"/>
+			<xs:import namespace="urn:common" schemaLocation="http://example.com/common.xsd"/>
+		</xs:schema>`)},
+		"local/types.xsd":   {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:types"/>`)},
+		"remote/common.xsd": {Data: []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:common"/>`)},
+	}
+	l := NewLoader(fsys)
+	if err := l.AddCatalog("catalog.xml"); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := l.Load("export.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, s := range schemas {
+		got = append(got, s.Location)
+	}
+	want := []string{"fs:///export.xsd", "fs:///local/types.xsd", "fs:///remote/common.xsd"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package xsd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// NewDecoder returns XML decoder of documents in any charset known to
+// CharsetReader, including UTF-16 documents with byte order mark
+func NewDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(NewReader(r))
+	d.CharsetReader = CharsetReader
+	return d
+}
+
+// NewReader returns reader of the document which sniffs its byte order mark.
+// UTF-8 mark is dropped, UTF-16 documents are decoded into UTF-8, other
+// documents are read as is.
+func NewReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	bom, _ := br.Peek(3)
+
+	switch {
+	case bytes.HasPrefix(bom, bomUTF8):
+		br.Discard(len(bomUTF8))
+	case bytes.HasPrefix(bom, bomUTF16LE):
+		return transform.NewReader(br, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder())
+	case bytes.HasPrefix(bom, bomUTF16BE):
+		return transform.NewReader(br, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder())
+	}
+	return br
+}
+
+// CharsetReader returns reader decoding input in the charset into UTF-8, it
+// is used as xml.Decoder.CharsetReader. Charsets are looked up by IANA names
+// and aliases, then by labels of the WHATWG Encoding Standard, so
+// windows-1251, koi8-r, cp866 and iso-8859-5 are all known. UTF-16 input is
+// expected to be decoded by NewReader already, which is the case for
+// documents with byte order mark required by XML.
+func CharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(charset)), "utf-16") {
+		return input, nil
+	}
+
+	e, err := ianaindex.IANA.Encoding(charset)
+	if err != nil || e == nil {
+		e, err = htmlindex.Get(charset)
+	}
+	if err != nil || e == nil {
+		return nil, fmt.Errorf("unsupported charset: %q", charset)
+	}
+	return e.NewDecoder().Reader(input), nil
+}
@@ -0,0 +1,76 @@
+package xsd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestCharsetReader(t *testing.T) {
+	tests := []struct {
+		charset string
+		input   []byte
+	}{
+		{"windows-1251", []byte{0xf2, 0xe5, 0xf1, 0xf2}},
+		{"CP1251", []byte{0xf2, 0xe5, 0xf1, 0xf2}},
+		{"koi8-r", []byte{0xd4, 0xc5, 0xd3, 0xd4}},
+		{"cp866", []byte{0xe2, 0xa5, 0xe1, 0xe2}},
+		{"IBM866", []byte{0xe2, 0xa5, 0xe1, 0xe2}},
+		{"iso-8859-5", []byte{0xe2, 0xd5, 0xe1, 0xe2}},
+		{"utf-8", []byte("тест")},
+		{"UTF-16", []byte("тест")},
+	}
+	for _, tt := range tests {
+		r, err := CharsetReader(tt.charset, bytes.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("CharsetReader(%s): %v", tt.charset, err)
+			continue
+		}
+		if got, err := io.ReadAll(r); err != nil || string(got) != "тест" {
+			t.Errorf("CharsetReader(%s) reads %q, %v, want тест", tt.charset, got, err)
+		}
+	}
+
+	if _, err := CharsetReader("x-unknown", strings.NewReader("")); err == nil {
+		t.Error("unknown charset is supported")
+	}
+}
+
+func TestNewDecoder(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="%s"?><doc>тест</doc>`
+	cp1251, _ := charmap.Windows1251.NewEncoder().String(strings.Replace(doc, "%s", "windows-1251", 1))
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().String(strings.Replace(doc, "%s", "UTF-16", 1))
+	utf16BE, _ := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().String(strings.Replace(doc, "%s", "UTF-16", 1))
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"windows-1251", cp1251},
+		{"UTF-8 with BOM", "\xef\xbb\xbf" + strings.Replace(doc, "%s", "UTF-8", 1)},
+		{"UTF-16LE with BOM", utf16},
+		{"UTF-16BE with BOM", utf16BE},
+	}
+	for _, tt := range tests {
+		var v struct {
+			Text string `xml:",chardata"`
+		}
+		if err := NewDecoder(strings.NewReader(tt.doc)).Decode(&v); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if v.Text != "тест" {
+			t.Errorf("%s: decoded %q, want тест", tt.name, v.Text)
+		}
+	}
+
+	// encoding/xml does not know charsets without CharsetReader
+	if err := xml.NewDecoder(strings.NewReader(cp1251)).Decode(new(struct{})); err == nil {
+		t.Error("windows-1251 is decoded by encoding/xml")
+	}
+}
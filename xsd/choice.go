@@ -43,5 +43,6 @@ func (c Choice) GetAllElements() []Element {
 		}
 	}
 
-	return elements
+	// only one of the alternatives is present
+	return optional(elements)
 }
@@ -0,0 +1,68 @@
+package xsd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// SniffVersion reads the name of the root element of the document and the
+// version given by the attribute of the root or of its first child element,
+// like schemeVersion of zakupki.gov.ru documents. Version is nil if the
+// attribute is absent.
+func SniffVersion(r io.Reader, attr string) (xml.Name, Version, error) {
+	var root xml.Name
+	d := NewDecoder(r)
+	for depth := 0; depth < 2; {
+		t, err := d.Token()
+		if err == io.EOF && depth > 0 {
+			break
+		}
+		if err != nil {
+			return root, nil, fmt.Errorf("could not read root element: %v", err)
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				root = t.Name
+			}
+			for _, a := range t.Attr {
+				if a.Name.Local == attr {
+					version, err := ParseVersion(a.Value)
+					if err != nil {
+						return root, nil, err
+					}
+					return root, version, nil
+				}
+			}
+			depth++
+		case xml.EndElement:
+			return root, nil, nil
+		}
+	}
+	return root, nil, nil
+}
+
+// DecodeVersioned decodes the document into the value returned by newValue
+// for its root element and version given by the attribute, see SniffVersion
+func DecodeVersioned(r io.Reader, attr string, newValue func(xml.Name, Version) (interface{}, error)) (interface{}, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	name, version, err := SniffVersion(bytes.NewReader(data), attr)
+	if err != nil {
+		return nil, err
+	}
+	v, err := newValue(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -2,14 +2,35 @@ package xsd
 
 // Element http://www.w3schools.com/xml/el_element.asp
 type Element struct {
-	Name        string       `xml:"name,attr"`
-	Type        string       `xml:"type,attr"`
-	Default     string       `xml:"default,attr"`
-	Min         string       `xml:"minOccurs,attr"`
-	Max         string       `xml:"maxOccurs,attr"`
-	Annotation  string       `xml:"annotation>documentation"`
-	ComplexType *ComplexType `xml:"complexType"` // inline complex type
-	SimpleType  *SimpleType  `xml:"simpleType"`  // inline simple type
+	Name        string               `xml:"name,attr"`
+	Type        string               `xml:"type,attr"`
+	Default     string               `xml:"default,attr"`
+	Nillable    bool                 `xml:"nillable,attr"`
+	Min         string               `xml:"minOccurs,attr"`
+	Max         string               `xml:"maxOccurs,attr"`
+	Annotation  string               `xml:"annotation>documentation"`
+	ComplexType *ComplexType         `xml:"complexType"` // inline complex type
+	SimpleType  *SimpleType          `xml:"simpleType"`  // inline simple type
+	Keys        []IdentityConstraint `xml:"key"`
+	KeyRefs     []IdentityConstraint `xml:"keyref"`
+	Uniques     []IdentityConstraint `xml:"unique"`
+}
+
+// IdentityConstraints returns key, keyref and unique constraints of the
+// element with their kinds set
+func (e Element) IdentityConstraints() []IdentityConstraint {
+	var res []IdentityConstraint
+	add := func(kind string, list []IdentityConstraint) {
+		for _, c := range list {
+			c.Kind = kind
+			res = append(res, c)
+		}
+	}
+
+	add(IdentityKey, e.Keys)
+	add(IdentityUnique, e.Uniques)
+	add(IdentityKeyRef, e.KeyRefs)
+	return res
 }
 
 func (e Element) IsInlineType() bool {
@@ -0,0 +1,164 @@
+package xsd
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Facets holds constraining facets of a simple type which are checked by
+// generated Validate methods. Zero value of a length facet means the facet
+// is absent. Patterns hold XSD regular expressions, one per derivation
+// step, and the value must match all of them. Enumeration holds allowed
+// values of the most derived type declaring them.
+type Facets struct {
+	WhiteSpace  string
+	Length      int
+	MinLength   int
+	MaxLength   int
+	Patterns    []string
+	Enumeration []string
+}
+
+// Check normalizes the value according to the whiteSpace facet and checks
+// it against the length, pattern and enumeration facets
+func (f Facets) Check(value string) error {
+	value = NormalizeWhiteSpace(value, f.WhiteSpace)
+	n := utf8.RuneCountInString(value)
+
+	if f.Length > 0 && n != f.Length {
+		return fmt.Errorf("length of %q is %d, must be %d", value, n, f.Length)
+	}
+	if f.MinLength > 0 && n < f.MinLength {
+		return fmt.Errorf("length of %q is %d, must be at least %d", value, n, f.MinLength)
+	}
+	if f.MaxLength > 0 && n > f.MaxLength {
+		return fmt.Errorf("length of %q is %d, must be at most %d", value, n, f.MaxLength)
+	}
+
+	for _, p := range f.Patterns {
+		ok, err := MatchPattern(p, value)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%q does not match pattern %q", value, p)
+		}
+	}
+
+	if len(f.Enumeration) > 0 {
+		for _, e := range f.Enumeration {
+			if NormalizeWhiteSpace(e, f.WhiteSpace) == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of enumeration values", value)
+	}
+	return nil
+}
+
+// IsEmpty returns true if there is nothing to check besides whitespace
+// normalization
+func (f Facets) IsEmpty() bool {
+	return f.Length == 0 && f.MinLength == 0 && f.MaxLength == 0 && len(f.Patterns) == 0 && len(f.Enumeration) == 0
+}
+
+// String returns facets like whiteSpace=collapse maxLength=10, separated by
+// spaces
+func (f Facets) String() string {
+	var parts []string
+	if f.WhiteSpace != "" {
+		parts = append(parts, "whiteSpace="+f.WhiteSpace)
+	}
+	if f.Length > 0 {
+		parts = append(parts, fmt.Sprintf("length=%d", f.Length))
+	}
+	if f.MinLength > 0 {
+		parts = append(parts, fmt.Sprintf("minLength=%d", f.MinLength))
+	}
+	if f.MaxLength > 0 {
+		parts = append(parts, fmt.Sprintf("maxLength=%d", f.MaxLength))
+	}
+	for _, p := range f.Patterns {
+		parts = append(parts, fmt.Sprintf("pattern=%q", p))
+	}
+	for _, e := range f.Enumeration {
+		parts = append(parts, fmt.Sprintf("enumeration=%q", e))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Inherit fills facets that are not set yet from the restriction. Facets of
+// a derived type are collected first, so they take precedence over the base
+// type ones.
+func (f *Facets) Inherit(r Restriction) {
+	if f.WhiteSpace == "" {
+		f.WhiteSpace = r.WhiteSpace.Value
+	}
+	if f.Length == 0 {
+		f.Length = r.Length.Int()
+	}
+	if f.MinLength == 0 {
+		f.MinLength = r.MinLength.Int()
+	}
+	if f.MaxLength == 0 {
+		f.MaxLength = r.MaxLength.Int()
+	}
+	if len(f.Enumeration) == 0 {
+		for _, e := range r.Enumeration {
+			f.Enumeration = append(f.Enumeration, e.Value)
+		}
+	}
+
+	// patterns of the same step are alternatives
+	switch len(r.Patterns) {
+	case 0:
+	case 1:
+		f.Patterns = append(f.Patterns, r.Patterns[0].Value)
+	default:
+		alts := make([]string, len(r.Patterns))
+		for i, p := range r.Patterns {
+			alts[i] = "(" + p.Value + ")"
+		}
+		f.Patterns = append(f.Patterns, strings.Join(alts, "|"))
+	}
+}
+
+// IsZero reports whether the value of an optional field is absent. Fields
+// of generated structs are not pointers, so absence can not be told from the
+// zero value.
+func IsZero(v interface{}) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+// FieldError is a validation error of a struct field
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// NewFieldError wraps err with the field name, nested field errors are
+// joined into a path
+func NewFieldError(field string, err error) error {
+	if fe, ok := err.(*FieldError); ok {
+		return &FieldError{Field: field + "." + fe.Field, Err: fe.Err}
+	}
+	return &FieldError{Field: field, Err: err}
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Int returns facet value as int, or 0 if it is absent or malformed
+func (f Facet) Int() int {
+	i, _ := strconv.Atoi(f.Value)
+	return i
+}
@@ -0,0 +1,56 @@
+package xsd
+
+import "testing"
+
+func TestFacetsCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		facets Facets
+		value  string
+		ok     bool
+	}{
+		{"no facets", Facets{}, " any ", true},
+		{"length", Facets{Length: 3}, "abc", true},
+		{"short", Facets{Length: 3}, "ab", false},
+		{"long", Facets{Length: 3}, "abcd", false},
+		{"runes", Facets{Length: 4}, "тест", true},
+		{"min length of runes", Facets{MinLength: 5}, "тест", false},
+		{"max length of runes", Facets{MaxLength: 4}, "тесты", false},
+		{"length in range", Facets{MinLength: 2, MaxLength: 4}, "abc", true},
+		{"preserved spaces", Facets{MaxLength: 3}, " ab ", false},
+		{"collapsed spaces", Facets{WhiteSpace: WhiteSpaceCollapse, MaxLength: 3}, " a\n\tb ", true},
+		{"replaced spaces", Facets{WhiteSpace: WhiteSpaceReplace, Length: 4}, "a\tb\n", true},
+		{"pattern", Facets{Patterns: []string{`\d{3}`}}, "123", true},
+		{"pattern mismatch", Facets{Patterns: []string{`\d{3}`}}, "12a", false},
+		{"collapsed pattern", Facets{WhiteSpace: WhiteSpaceCollapse, Patterns: []string{`\d{3}`}}, " 123\n", true},
+		{"all patterns", Facets{Patterns: []string{`\d+`, `.{2}`}}, "123", false},
+		{"enumeration", Facets{Enumeration: []string{"a", "b"}}, "b", true},
+		{"not enumerated", Facets{Enumeration: []string{"a", "b"}}, "c", false},
+		{"collapsed enumeration", Facets{WhiteSpace: WhiteSpaceCollapse, Enumeration: []string{" a  b "}}, "a\nb", true},
+	}
+	for _, tt := range tests {
+		if err := tt.facets.Check(tt.value); (err == nil) != tt.ok {
+			t.Errorf("%s: Check(%q) = %v, want ok %v", tt.name, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeWhiteSpace(t *testing.T) {
+	tests := []struct {
+		mode  string
+		value string
+		want  string
+	}{
+		{"", " a\t\n b\r ", " a\t\n b\r "},
+		{WhiteSpacePreserve, " a\t\n b\r ", " a\t\n b\r "},
+		{WhiteSpaceReplace, " a\t\n b\r ", " a   b  "},
+		{WhiteSpaceCollapse, " a\t\n b\r ", "a b"},
+		{WhiteSpaceCollapse, "\u00a0a  б ", "\u00a0a б"},
+		{WhiteSpaceCollapse, " \n ", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeWhiteSpace(tt.value, tt.mode); got != tt.want {
+			t.Errorf("NormalizeWhiteSpace(%q, %q) = %q, want %q", tt.value, tt.mode, got, tt.want)
+		}
+	}
+}
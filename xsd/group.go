@@ -36,5 +36,8 @@ func (g Group) GetAllElements() []Element {
 		}
 	}
 
+	if g.Min == "0" {
+		return optional(elements)
+	}
 	return elements
 }
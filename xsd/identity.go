@@ -0,0 +1,361 @@
+package xsd
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Kinds of identity constraints
+const (
+	IdentityKey    = "key"
+	IdentityKeyRef = "keyref"
+	IdentityUnique = "unique"
+)
+
+// IdentityConstraint represents xs:key, xs:keyref and xs:unique
+// http://www.w3schools.com/xml/el_key.asp
+type IdentityConstraint struct {
+	Kind     string   `xml:"-"`
+	Name     string   `xml:"name,attr"`
+	Refer    string   `xml:"refer,attr"`
+	Selector Selector `xml:"selector"`
+	Fields   []Field  `xml:"field"`
+}
+
+// Selector http://www.w3schools.com/xml/el_selector.asp
+type Selector struct {
+	XPath string `xml:"xpath,attr"`
+}
+
+// Field http://www.w3schools.com/xml/el_field.asp
+type Field struct {
+	XPath string `xml:"xpath,attr"`
+}
+
+// IdentityError reports a duplicate key or a reference to a missing key
+type IdentityError struct {
+	Constraint string
+	Kind       string
+	Values     []string
+	Reason     string
+}
+
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("%s %q %v: %s", e.Kind, e.Constraint, e.Values, e.Reason)
+}
+
+// CheckIdentityConstraints evaluates identity constraints declared on an
+// element over its decoded value v and reports duplicate keys and keyrefs
+// which do not match any key. Selectors and fields use the restricted XPath
+// subset of XSD, which is evaluated by xml tags of struct fields. Namespace
+// prefixes of name tests are ignored. Zero values of fields are treated as
+// absent, as they are in the rest of generated code.
+// https://www.w3.org/TR/xmlschema-1/#coss-identity-constraint
+func CheckIdentityConstraints(v interface{}, constraints []IdentityConstraint) error {
+	root := reflect.ValueOf(v)
+	tables := make(map[string]map[string]bool)
+	var errs []error
+
+	// keys are collected first, so keyrefs may precede them
+	for _, c := range constraints {
+		if c.Kind == IdentityKeyRef {
+			continue
+		}
+		tuples, err := evalConstraint(root, c)
+		if err != nil {
+			return err
+		}
+
+		table := make(map[string]bool)
+		for _, t := range tuples {
+			if t == nil {
+				if c.Kind == IdentityKey {
+					errs = append(errs, &IdentityError{Constraint: c.Name, Kind: c.Kind, Reason: "key field is absent"})
+				}
+				continue
+			}
+			k := strings.Join(t, "\x00")
+			if table[k] {
+				errs = append(errs, &IdentityError{Constraint: c.Name, Kind: c.Kind, Values: t, Reason: "duplicate value"})
+			}
+			table[k] = true
+		}
+		tables[c.Name] = table
+	}
+
+	for _, c := range constraints {
+		if c.Kind != IdentityKeyRef {
+			continue
+		}
+		table, ok := tables[stripPrefix(c.Refer)]
+		if !ok {
+			return fmt.Errorf("keyref %q refers to unknown key %q", c.Name, c.Refer)
+		}
+		tuples, err := evalConstraint(root, c)
+		if err != nil {
+			return err
+		}
+		for _, t := range tuples {
+			if t != nil && !table[strings.Join(t, "\x00")] {
+				errs = append(errs, &IdentityError{Constraint: c.Name, Kind: c.Kind, Values: t, Reason: "no matching key " + c.Refer})
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// evalConstraint returns field values of every node selected by the
+// constraint, nil tuple means some of the fields are absent
+func evalConstraint(root reflect.Value, c IdentityConstraint) ([][]string, error) {
+	selector, err := parseXPath(c.Selector.XPath, false)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]xpathUnion, len(c.Fields))
+	for i, f := range c.Fields {
+		if fields[i], err = parseXPath(f.XPath, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var tuples [][]string
+	for _, n := range selector.eval(root) {
+		tuple := make([]string, len(fields))
+		for i, f := range fields {
+			values := f.values(n)
+			if len(values) > 1 {
+				return nil, fmt.Errorf("%s %q: field %q selects more than one value", c.Kind, c.Name, c.Fields[i].XPath)
+			}
+			if len(values) == 0 {
+				tuple = nil
+				break
+			}
+			tuple[i] = values[0]
+		}
+		tuples = append(tuples, tuple)
+	}
+	return tuples, nil
+}
+
+type xpathStep struct {
+	name       string
+	attr       bool
+	self       bool
+	descendant bool
+}
+
+type xpathPath []xpathStep
+
+type xpathUnion []xpathPath
+
+// parseXPath parses the XPath subset of selectors and fields
+// https://www.w3.org/TR/xmlschema-1/#coss-identity-constraint
+func parseXPath(expr string, field bool) (xpathUnion, error) {
+	var union xpathUnion
+	for _, alt := range strings.Split(expr, "|") {
+		alt = strings.TrimSpace(alt)
+		descendant := false
+		if strings.HasPrefix(alt, ".//") {
+			descendant = true
+			alt = strings.TrimPrefix(alt, ".//")
+		}
+
+		var path xpathPath
+		parts := strings.Split(alt, "/")
+		for i, part := range parts {
+			part = strings.TrimPrefix(strings.TrimSpace(part), "child::")
+			step := xpathStep{descendant: descendant && i == 0}
+			switch {
+			case part == ".":
+				step.self = true
+			case strings.HasPrefix(part, "@"), strings.HasPrefix(part, "attribute::"):
+				if !field || i != len(parts)-1 {
+					return nil, fmt.Errorf("xpath %q: attribute is allowed only as the last step of a field", expr)
+				}
+				step.attr = true
+				step.name = stripPrefix(strings.TrimPrefix(strings.TrimPrefix(part, "@"), "attribute::"))
+			case part == "":
+				return nil, fmt.Errorf("xpath %q: empty step", expr)
+			default:
+				step.name = stripPrefix(part)
+			}
+			path = append(path, step)
+		}
+		union = append(union, path)
+	}
+	return union, nil
+}
+
+func stripPrefix(name string) string {
+	if i := strings.IndexByte(name, ':'); i >= 0 && name[i+1:] != "*" {
+		return name[i+1:]
+	}
+	if strings.HasSuffix(name, ":*") {
+		return "*"
+	}
+	return name
+}
+
+// eval returns nodes selected by the union
+func (u xpathUnion) eval(root reflect.Value) []reflect.Value {
+	var res []reflect.Value
+	for _, p := range u {
+		nodes := []reflect.Value{root}
+		for _, s := range p {
+			var next []reflect.Value
+			for _, n := range nodes {
+				next = append(next, s.eval(n)...)
+			}
+			nodes = next
+		}
+		res = append(res, nodes...)
+	}
+	return res
+}
+
+// eval applies the step to a node, attribute steps return the attribute
+// value as a node
+func (s xpathStep) eval(n reflect.Value) []reflect.Value {
+	if s.self {
+		return []reflect.Value{n}
+	}
+
+	var res []reflect.Value
+	st, ok := structOf(n)
+	if !ok {
+		return nil
+	}
+	for i, f := range xmlFields(st.Type()) {
+		if f.skip || f.chardata || f.attr != s.attr {
+			continue
+		}
+		items := elementItems(st.Field(i))
+		if f.name == s.name || s.name == "*" {
+			res = append(res, items...)
+		}
+		if s.descendant && !f.attr {
+			for _, item := range items {
+				res = append(res, s.eval(item)...)
+			}
+		}
+	}
+	return res
+}
+
+// values returns string values of the nodes selected by the field
+func (u xpathUnion) values(n reflect.Value) []string {
+	var res []string
+	for _, node := range u.eval(n) {
+		if v, ok := nodeValue(node); ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+type xmlField struct {
+	name     string
+	attr     bool
+	chardata bool
+	skip     bool
+}
+
+// xmlFields returns xml names of struct fields by their index
+func xmlFields(t reflect.Type) []xmlField {
+	fields := make([]xmlField, t.NumField())
+	for i := range fields {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("xml")
+		if sf.PkgPath != "" || tag == "-" || sf.Name == "XMLName" {
+			fields[i].skip = true
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		fields[i].name = opts[0]
+		if fields[i].name == "" {
+			fields[i].name = sf.Name
+		}
+		for _, o := range opts[1:] {
+			switch o {
+			case "attr":
+				fields[i].attr = true
+			case "chardata":
+				fields[i].chardata = true
+			case "innerxml", "comment", "any":
+				fields[i].skip = true
+			}
+		}
+	}
+	return fields
+}
+
+// nillableValue is implemented by Nillable
+type nillableValue interface {
+	nillableValue() (interface{}, bool)
+}
+
+// elementItems expands lists and nillable wrappers of a field value
+func elementItems(v reflect.Value) []reflect.Value {
+	if n, ok := v.Interface().(nillableValue); ok {
+		value, isNil := n.nillableValue()
+		if isNil {
+			return nil
+		}
+		return []reflect.Value{reflect.ValueOf(value)}
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		var res []reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			res = append(res, elementItems(v.Index(i))...)
+		}
+		return res
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return elementItems(v.Elem())
+	}
+	return []reflect.Value{v}
+}
+
+func structOf(n reflect.Value) (reflect.Value, bool) {
+	for n.Kind() == reflect.Ptr || n.Kind() == reflect.Interface {
+		if n.IsNil() {
+			return n, false
+		}
+		n = n.Elem()
+	}
+	return n, n.Kind() == reflect.Struct
+}
+
+// nodeValue returns typed value of a node as a string, zero values are
+// absent
+func nodeValue(n reflect.Value) (string, bool) {
+	if !n.IsValid() || n.IsZero() {
+		return "", false
+	}
+	if m, ok := n.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err == nil
+	}
+
+	if st, ok := structOf(n); ok {
+		for i, f := range xmlFields(st.Type()) {
+			if f.chardata && !f.skip {
+				return nodeValue(st.Field(i))
+			}
+		}
+		return "", false
+	}
+	return fmt.Sprint(n.Interface()), true
+}
@@ -0,0 +1,155 @@
+package xsd
+
+import (
+	"errors"
+	"testing"
+)
+
+type testCatalog struct {
+	Books  []testBook  `xml:"book"`
+	Orders []testOrder `xml:"order"`
+}
+
+type testBook struct {
+	ID    string `xml:"id,attr"`
+	ISBN  string `xml:"isbn"`
+	Title string `xml:"title"`
+}
+
+type testOrder struct {
+	Lines []testLine `xml:"line"`
+}
+
+type testLine struct {
+	Book     string    `xml:"book"`
+	Gift     *testLine `xml:"gift"`
+	Comments []string  `xml:"comment"`
+}
+
+func TestCheckIdentityConstraints(t *testing.T) {
+	bookKey := IdentityConstraint{Kind: IdentityKey, Name: "bookKey",
+		Selector: Selector{XPath: "book"}, Fields: []Field{{XPath: "@id"}}}
+	lineRef := IdentityConstraint{Kind: IdentityKeyRef, Name: "lineRef", Refer: "tns:bookKey",
+		Selector: Selector{XPath: "order/line"}, Fields: []Field{{XPath: "book"}}}
+	giftRef := IdentityConstraint{Kind: IdentityKeyRef, Name: "giftRef", Refer: "bookKey",
+		Selector: Selector{XPath: ".//gift"}, Fields: []Field{{XPath: "book"}}}
+	isbnUnique := IdentityConstraint{Kind: IdentityUnique, Name: "isbnUnique",
+		Selector: Selector{XPath: "book"}, Fields: []Field{{XPath: "isbn"}}}
+	titleKey := IdentityConstraint{Kind: IdentityKey, Name: "titleKey",
+		Selector: Selector{XPath: "./book"}, Fields: []Field{{XPath: "title"}, {XPath: "@id"}}}
+
+	books := []testBook{{ID: "1", ISBN: "a", Title: "X"}, {ID: "2", Title: "X"}, {ID: "3"}}
+	tests := []struct {
+		name        string
+		v           testCatalog
+		constraints []IdentityConstraint
+		// errs are constraints of expected identity errors
+		errs []string
+	}{
+		{
+			name:        "valid",
+			v:           testCatalog{Books: books, Orders: []testOrder{{Lines: []testLine{{Book: "1"}, {Book: "3"}}}}},
+			constraints: []IdentityConstraint{lineRef, bookKey, isbnUnique},
+		},
+		{
+			name:        "duplicate key",
+			v:           testCatalog{Books: append(books, testBook{ID: "2"})},
+			constraints: []IdentityConstraint{bookKey},
+			errs:        []string{"bookKey"},
+		},
+		{
+			name:        "absent key field",
+			v:           testCatalog{Books: append(books, testBook{Title: "Y"})},
+			constraints: []IdentityConstraint{bookKey},
+			errs:        []string{"bookKey"},
+		},
+		{
+			name:        "absent unique field",
+			v:           testCatalog{Books: append(books, testBook{ID: "4"})},
+			constraints: []IdentityConstraint{isbnUnique},
+		},
+		{
+			name:        "duplicate unique",
+			v:           testCatalog{Books: append(books, testBook{ID: "4", ISBN: "a"})},
+			constraints: []IdentityConstraint{isbnUnique},
+			errs:        []string{"isbnUnique"},
+		},
+		{
+			name:        "compound key",
+			v:           testCatalog{Books: append(books, testBook{ID: "1", Title: "Y"})},
+			constraints: []IdentityConstraint{titleKey},
+			errs:        []string{"titleKey"},
+		},
+		{
+			name:        "missing keyref",
+			v:           testCatalog{Books: books, Orders: []testOrder{{Lines: []testLine{{Book: "1"}, {Book: "5"}}}}},
+			constraints: []IdentityConstraint{bookKey, lineRef},
+			errs:        []string{"lineRef"},
+		},
+		{
+			name:        "absent keyref field",
+			v:           testCatalog{Books: books, Orders: []testOrder{{Lines: []testLine{{}}}}},
+			constraints: []IdentityConstraint{bookKey, lineRef},
+		},
+		{
+			name: "nested descendant selector",
+			v: testCatalog{Books: books, Orders: []testOrder{{Lines: []testLine{
+				{Book: "1", Gift: &testLine{Book: "2", Gift: &testLine{Book: "7"}}},
+			}}}},
+			constraints: []IdentityConstraint{bookKey, giftRef},
+			errs:        []string{"giftRef"},
+		},
+	}
+	for _, tt := range tests {
+		err := CheckIdentityConstraints(tt.v, tt.constraints)
+		var got []string
+		for _, e := range unwrapErrors(err) {
+			var ierr *IdentityError
+			if !errors.As(e, &ierr) {
+				t.Errorf("%s: unexpected error %v", tt.name, e)
+				continue
+			}
+			got = append(got, ierr.Constraint)
+		}
+		if len(got) != len(tt.errs) {
+			t.Errorf("%s: errors of %v, want %v: %v", tt.name, got, tt.errs, err)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.errs[i] {
+				t.Errorf("%s: errors of %v, want %v: %v", tt.name, got, tt.errs, err)
+			}
+		}
+	}
+}
+
+func TestCheckIdentityConstraintsInvalid(t *testing.T) {
+	v := testCatalog{Orders: []testOrder{{Lines: []testLine{{Book: "1", Comments: []string{"a", "b"}}}}}}
+	tests := []IdentityConstraint{
+		{Kind: IdentityKeyRef, Name: "ref", Refer: "missing",
+			Selector: Selector{XPath: "order/line"}, Fields: []Field{{XPath: "book"}}},
+		{Kind: IdentityUnique, Name: "multiple",
+			Selector: Selector{XPath: "order/line"}, Fields: []Field{{XPath: "comment"}}},
+		{Kind: IdentityUnique, Name: "attribute selector",
+			Selector: Selector{XPath: "order/@id"}, Fields: []Field{{XPath: "."}}},
+		{Kind: IdentityUnique, Name: "empty step",
+			Selector: Selector{XPath: "order//line"}, Fields: []Field{{XPath: "book"}}},
+	}
+	for _, c := range tests {
+		err := CheckIdentityConstraints(v, []IdentityConstraint{c})
+		var ierr *IdentityError
+		if err == nil || errors.As(err, &ierr) {
+			t.Errorf("%s: error = %v, want an error of the constraint", c.Name, err)
+		}
+	}
+}
+
+func unwrapErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
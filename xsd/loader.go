@@ -0,0 +1,377 @@
+package xsd
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Loader reads schemas with all schemas they import and include. Schemas
+// are read from a fs.FS, or from the OS filesystem if it is nil. Locations
+// of http:// schemas are mapped to local copies by the catalog. Parsed
+// schemas are cached by their absolute URI, so each file is read once and
+// files with the same name in different directories are told apart.
+type Loader struct {
+	// CharsetReader decodes schemas in encodings other than UTF-8, it is
+	// CharsetReader of the package by default
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+	// Catalog maps locations of schemas to local copies
+	Catalog *Catalog
+	// VersionDetectors detect versions of loaded schemas, they are
+	// DefaultVersionDetectors by default
+	VersionDetectors []VersionDetector
+
+	fsys   fs.FS
+	closer io.Closer
+	cache  map[string]*Schema
+}
+
+// NewLoader returns a loader of schemas from the file system, nil means
+// the OS filesystem
+func NewLoader(fsys fs.FS) *Loader {
+	return &Loader{
+		CharsetReader:    CharsetReader,
+		Catalog:          NewCatalog(),
+		VersionDetectors: DefaultVersionDetectors,
+		fsys:             fsys,
+		cache:            make(map[string]*Schema),
+	}
+}
+
+// NewZipLoader returns a loader of schemas from the zip archive, which must
+// be closed by Close. Names of files which are not marked as UTF-8 and are
+// not valid UTF-8 are read as cp866, which is used by Russian archivers.
+func NewZipLoader(name string) (*Loader, error) {
+	r, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		if f.Flags&zipUTF8 == 0 && !utf8.ValidString(f.Name) {
+			if f.Name, err = charmap.CodePage866.NewDecoder().String(f.Name); err != nil {
+				r.Close()
+				return nil, err
+			}
+		}
+	}
+	l := NewLoader(r)
+	l.closer = r
+	return l, nil
+}
+
+// zipUTF8 is the language encoding flag of zip file headers
+const zipUTF8 = 0x800
+
+// Close closes the archive of the loader
+func (l *Loader) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// AddCatalog reads the OASIS XML catalog located like schemas, including
+// the catalogs it refers to by nextCatalog
+func (l *Loader) AddCatalog(location string) error {
+	uri, err := l.uri(location)
+	if err != nil {
+		return err
+	}
+	return l.addCatalog(uri, make(map[string]bool))
+}
+
+func (l *Loader) addCatalog(uri string, seen map[string]bool) error {
+	if seen[uri] {
+		return nil
+	}
+	seen[uri] = true
+
+	r, err := l.open(uri)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	c, err := ParseCatalog(r, uri)
+	if err != nil {
+		return fmt.Errorf("could not read catalog %s: %v", uri, err)
+	}
+	l.Catalog.Merge(c)
+
+	for _, next := range c.next {
+		if err := l.addCatalog(next, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load returns the schema at location followed by all schemas it imports
+// and includes, each of them once
+func (l *Loader) Load(location string) ([]Schema, error) {
+	uri, err := l.uri(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []Schema
+	if err := l.load(uri, make(map[string]bool), &schemas); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+func (l *Loader) load(uri string, seen map[string]bool, schemas *[]Schema) error {
+	if local, ok := l.Catalog.Resolve(uri); ok {
+		uri = local
+	}
+	if seen[uri] {
+		return nil
+	}
+	seen[uri] = true
+
+	s, err := l.parse(uri)
+	if err != nil {
+		return err
+	}
+	*schemas = append(*schemas, *s)
+
+	base, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	for _, imp := range append(s.Imports, s.Includes...) {
+		location := imp.Location
+		if location == "" {
+			// the namespace may be mapped by the catalog
+			if _, ok := l.Catalog.Resolve(imp.Namespace); !ok {
+				continue
+			}
+			location = imp.Namespace
+		}
+		ref, err := url.Parse(location)
+		if err != nil {
+			return fmt.Errorf("invalid schemaLocation %q in %s: %v", location, uri, err)
+		}
+		if err := l.load(base.ResolveReference(ref).String(), seen, schemas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse returns the schema at the absolute URI, it is read once
+func (l *Loader) parse(uri string) (*Schema, error) {
+	if s, ok := l.cache[uri]; ok {
+		return s, nil
+	}
+
+	r, err := l.open(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	d := xml.NewDecoder(NewReader(bytes.NewReader(data)))
+	d.CharsetReader = l.CharsetReader
+	s, err := decodeSchema(d)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode schema %s: %v", uri, err)
+	}
+	s.Version = DetectVersion(s, l.VersionDetectors...)
+	s.Location = uri
+	hash := sha256.Sum256(data)
+	s.Hash = hex.EncodeToString(hash[:])
+
+	d = xml.NewDecoder(NewReader(bytes.NewReader(data)))
+	d.CharsetReader = l.CharsetReader
+	s.Lines = componentLines(d)
+	l.cache[uri] = s
+	return s, nil
+}
+
+// componentLines returns lines of top-level components of the schema by
+// their kinds and names, like "complexType zfcs_contractType"
+func componentLines(d *xml.Decoder) map[string]int {
+	lines := make(map[string]int)
+	depth := 0
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return lines
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			depth++
+			if depth != 2 {
+				continue
+			}
+			for _, a := range t.Attr {
+				if a.Name.Local == "name" {
+					line, _ := d.InputPos()
+					lines[t.Name.Local+" "+a.Value] = line
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// Open opens the file at location, it is used to read files of a schema
+// bundle which are not schemas
+func (l *Loader) Open(location string) (io.ReadCloser, error) {
+	uri, err := l.uri(location)
+	if err != nil {
+		return nil, err
+	}
+	return l.open(uri)
+}
+
+// Find returns location of the schema of the file system with the given
+// path or base name
+func (l *Loader) Find(name string) (string, error) {
+	files, err := l.schemaFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for _, f := range files {
+		if f == path.Clean(name) {
+			return f, nil
+		}
+		if path.Base(f) == name {
+			found = append(found, f)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no schema %s", name)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("schema name %s is ambiguous: %s", name, strings.Join(found, ", "))
+	}
+}
+
+// Roots returns locations of schemas of the file system which are not
+// imported or included by other schemas of it, sorted by path
+func (l *Loader) Roots() ([]string, error) {
+	files, err := l.schemaFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, f := range files {
+		uri, err := l.uri(f)
+		if err != nil {
+			return nil, err
+		}
+		s, err := l.parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		base, err := url.Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		for _, imp := range append(s.Imports, s.Includes...) {
+			ref, err := url.Parse(imp.Location)
+			if err != nil || imp.Location == "" {
+				continue
+			}
+			referenced[base.ResolveReference(ref).String()] = true
+		}
+	}
+
+	var roots []string
+	for _, f := range files {
+		if uri, _ := l.uri(f); !referenced[uri] {
+			roots = append(roots, f)
+		}
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("no root schemas, every schema is imported by another one")
+	}
+	return roots, nil
+}
+
+// schemaFiles returns paths of .xsd files of the file system
+func (l *Loader) schemaFiles() ([]string, error) {
+	if l.fsys == nil {
+		return nil, errors.New("loader of the OS filesystem can not list schemas")
+	}
+
+	var files []string
+	err := fs.WalkDir(l.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(path.Ext(p), ".xsd") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// uri returns absolute URI of a location given by a user. Paths are
+// relative to the root of the file system of the loader, or to the working
+// directory for the OS filesystem.
+func (l *Loader) uri(location string) (string, error) {
+	if u, err := url.Parse(location); err == nil && len(u.Scheme) > 1 {
+		return location, nil
+	}
+
+	if l.fsys != nil {
+		return (&url.URL{Scheme: "fs", Path: "/" + path.Clean(filepath.ToSlash(location))}).String(), nil
+	}
+	abs, err := filepath.Abs(location)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// open opens the file at the absolute URI
+func (l *Loader) open(uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "fs":
+		if l.fsys == nil {
+			return nil, fmt.Errorf("could not open %s: loader has no file system", uri)
+		}
+		return l.fsys.Open(strings.TrimPrefix(u.Path, "/"))
+	case "file":
+		// local copies of catalogs may be outside of the file system
+		return os.Open(filepath.FromSlash(u.Path))
+	default:
+		return nil, fmt.Errorf("could not open %s: no local copy in catalogs", uri)
+	}
+}
@@ -0,0 +1,102 @@
+package xsd
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// writeZip writes the archive of the files, names of which are stored in
+// cp866 without the UTF-8 flag like Russian archivers do
+func writeZip(t *testing.T, name string, files map[string]string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for file, content := range files {
+		encoded, err := charmap.CodePage866.NewEncoder().String(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: encoded, NonUTF8: true, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZipLoader(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "bundle.zip")
+	writeZip(t, name, map[string]string{
+		"Схемы/экспорт.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+			<xs:include schemaLocation="../Общие/типы.xsd"/>
+			<xs:element name="export" type="xs:string"/>
+		</xs:schema>`,
+		"Схемы/протокол.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+			<xs:include schemaLocation="../Общие/типы.xsd"/>
+		</xs:schema>`,
+		"Общие/типы.xsd":   `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"/>`,
+		"Общие/readme.txt": "not a schema",
+	})
+
+	l, err := NewZipLoader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	roots, err := l.Roots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Схемы/протокол.xsd", "Схемы/экспорт.xsd"}; !reflect.DeepEqual(roots, want) {
+		t.Errorf("Roots() = %v, want %v", roots, want)
+	}
+
+	location, err := l.Find("экспорт.xsd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := l.Load(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range schemas {
+		got = append(got, s.Location)
+	}
+	want := []string{"fs:///%D0%A1%D1%85%D0%B5%D0%BC%D1%8B/%D1%8D%D0%BA%D1%81%D0%BF%D0%BE%D1%80%D1%82.xsd",
+		"fs:///%D0%9E%D0%B1%D1%89%D0%B8%D0%B5/%D1%82%D0%B8%D0%BF%D1%8B.xsd"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
+
+func TestRootsCycle(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cycle.zip")
+	writeZip(t, name, map[string]string{
+		"a.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:include schemaLocation="b.xsd"/></xs:schema>`,
+		"b.xsd": `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:include schemaLocation="a.xsd"/></xs:schema>`,
+	})
+	l, err := NewZipLoader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if roots, err := l.Roots(); err == nil {
+		t.Errorf("Roots() = %v, want an error", roots)
+	}
+}
@@ -0,0 +1,62 @@
+package xsd
+
+import "encoding/xml"
+
+// XSINamespace is the XML Schema instance namespace of xsi:nil attribute
+const XSINamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// Nillable wraps value of an element declared with nillable="true", so
+// <foo xsi:nil="true"/> can be told from an empty element
+// https://www.w3.org/TR/xmlschema-1/#xsi_nil
+type Nillable[T any] struct {
+	Value T
+	Nil   bool
+}
+
+// UnmarshalXML implements xml.Unmarshaler
+func (n *Nillable[T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var zero T
+	n.Value = zero
+	n.Nil = false
+
+	for _, a := range start.Attr {
+		// prefix is left as is if the namespace is not declared
+		if a.Name.Local != "nil" || (a.Name.Space != XSINamespace && a.Name.Space != "xsi") {
+			continue
+		}
+		isNil, err := ParseBoolean(a.Value)
+		if err != nil {
+			return err
+		}
+		if isNil {
+			n.Nil = true
+			return d.Skip()
+		}
+	}
+
+	return d.DecodeElement(&n.Value, &start)
+}
+
+func (n Nillable[T]) nillableValue() (interface{}, bool) {
+	return n.Value, n.Nil
+}
+
+// MarshalXML implements xml.Marshaler. Zero value which is not nil is
+// omitted, like other fields of generated structs.
+func (n Nillable[T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if n.Nil {
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: XSINamespace},
+			xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"},
+		)
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+		return e.EncodeToken(start.End())
+	}
+
+	if IsZero(n.Value) {
+		return nil
+	}
+	return e.EncodeElement(n.Value, start)
+}
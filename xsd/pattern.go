@@ -0,0 +1,448 @@
+package xsd
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// PatternError describes a construct of XSD regular expression which can not
+// be translated into Go regexp
+type PatternError struct {
+	Pattern string
+	Offset  int
+	Reason  string
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("pattern %q at offset %d: %s", e.Pattern, e.Offset, e.Reason)
+}
+
+// TranslatePattern converts XSD regular expression into RE2 syntax used by
+// Go regexp package. XSD patterns are implicitly anchored, use Unicode
+// semantics of \d, \w and \s, have \i and \c name escapes, \p{IsBlock} block
+// escapes and character class subtraction, all of which are rewritten.
+// https://www.w3.org/TR/xmlschema-2/#regexs
+func TranslatePattern(pattern string) (string, error) {
+	p := &patternParser{pattern: pattern, src: []rune(pattern)}
+
+	var b strings.Builder
+	b.WriteString(`\A(?:`)
+	for p.pos < len(p.src) {
+		if err := p.atom(&b); err != nil {
+			return "", err
+		}
+	}
+	b.WriteString(`)\z`)
+
+	res := b.String()
+	if _, err := syntax.Parse(res, syntax.Perl); err != nil {
+		return "", &PatternError{Pattern: pattern, Reason: err.Error()}
+	}
+	return res, nil
+}
+
+// CompilePattern translates XSD pattern and compiles it
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	expr, err := TranslatePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile(expr)
+}
+
+var compiledPatterns sync.Map
+
+// MatchPattern reports whether the value matches XSD pattern. Compiled
+// patterns are cached.
+func MatchPattern(pattern, value string) (bool, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp).MatchString(value), nil
+	}
+
+	re, err := CompilePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+	compiledPatterns.Store(pattern, re)
+	return re.MatchString(value), nil
+}
+
+type patternParser struct {
+	pattern string
+	src     []rune
+	pos     int
+}
+
+func (p *patternParser) errorf(format string, args ...interface{}) error {
+	return &PatternError{Pattern: p.pattern, Offset: p.pos, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (p *patternParser) peek(n int) rune {
+	if p.pos+n < len(p.src) {
+		return p.src[p.pos+n]
+	}
+	return 0
+}
+
+// atom translates a single token outside of character class
+func (p *patternParser) atom(b *strings.Builder) error {
+	c := p.src[p.pos]
+	switch c {
+	case '\\':
+		set, err := p.escape()
+		if err != nil {
+			return err
+		}
+		b.WriteString(set.String())
+	case '[':
+		set, err := p.class()
+		if err != nil {
+			return err
+		}
+		b.WriteString(set.String())
+	case '.':
+		// XSD wildcard excludes both line feed and carriage return
+		b.WriteString(`[^\n\r]`)
+		p.pos++
+	case '^', '$':
+		// not anchors in XSD
+		b.WriteString(`\` + string(c))
+		p.pos++
+	case '(':
+		if p.peek(1) == '?' {
+			return p.errorf("group modifiers are not allowed")
+		}
+		b.WriteRune(c)
+		p.pos++
+	case ']':
+		return p.errorf("unescaped ']'")
+	default:
+		b.WriteRune(c)
+		p.pos++
+	}
+	return nil
+}
+
+// escape parses an escape sequence starting at backslash and returns
+// characters it matches
+func (p *patternParser) escape() (runeSet, error) {
+	p.pos++
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("trailing backslash")
+	}
+
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'n':
+		return runeSet{{'\n', '\n'}}, nil
+	case 'r':
+		return runeSet{{'\r', '\r'}}, nil
+	case 't':
+		return runeSet{{'\t', '\t'}}, nil
+	case '\\', '|', '.', '-', '^', '?', '*', '+', '{', '}', '(', ')', '[', ']':
+		return runeSet{{c, c}}, nil
+	case 's':
+		return spaceChars, nil
+	case 'S':
+		return spaceChars.complement(), nil
+	case 'i':
+		return nameStartChars, nil
+	case 'I':
+		return nameStartChars.complement(), nil
+	case 'c':
+		return nameChars, nil
+	case 'C':
+		return nameChars.complement(), nil
+	case 'd':
+		return tableSet(unicode.Nd), nil
+	case 'D':
+		return tableSet(unicode.Nd).complement(), nil
+	case 'w':
+		return wordChars().complement(), nil
+	case 'W':
+		return wordChars(), nil
+	case 'p', 'P':
+		set, err := p.property()
+		if err != nil {
+			return nil, err
+		}
+		if c == 'P' {
+			return set.complement(), nil
+		}
+		return set, nil
+	}
+
+	p.pos--
+	return nil, p.errorf("unsupported escape '\\%c'", c)
+}
+
+// property parses {Category} or {IsBlock} part of \p and \P escapes
+func (p *patternParser) property() (runeSet, error) {
+	if p.peek(0) != '{' {
+		return nil, p.errorf("expected '{' after \\p")
+	}
+	end := p.pos
+	for end < len(p.src) && p.src[end] != '}' {
+		end++
+	}
+	if end == len(p.src) {
+		return nil, p.errorf("unterminated property escape")
+	}
+
+	name := string(p.src[p.pos+1 : end])
+	if strings.HasPrefix(name, "Is") {
+		block := strings.TrimPrefix(name, "Is")
+		if surrogateBlocks[block] {
+			return nil, p.errorf("surrogate block %q can not be matched", block)
+		}
+		set, ok := unicodeBlocks[block]
+		if !ok {
+			return nil, p.errorf("unknown block %q", block)
+		}
+		p.pos = end + 1
+		return set, nil
+	}
+
+	t, ok := unicode.Categories[name]
+	if !ok {
+		return nil, p.errorf("unsupported category %q", name)
+	}
+	p.pos = end + 1
+	return tableSet(t), nil
+}
+
+// class parses character class expression including subtraction
+func (p *patternParser) class() (runeSet, error) {
+	p.pos++
+	negative := false
+	if p.peek(0) == '^' {
+		negative = true
+		p.pos++
+	}
+
+	var set, sub runeSet
+	for first := true; ; first = false {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated character class")
+		}
+
+		c := p.src[p.pos]
+		if c == ']' {
+			if first {
+				return nil, p.errorf("empty character class")
+			}
+			p.pos++
+			break
+		}
+		if c == '-' && p.peek(1) == '[' && !first {
+			p.pos++
+			s, err := p.class()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek(0) != ']' {
+				return nil, p.errorf("subtraction must be the last part of character class")
+			}
+			p.pos++
+			sub = s
+			break
+		}
+		if c == '[' {
+			return nil, p.errorf("unescaped '[' in character class")
+		}
+
+		item, err := p.classItem()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, item...)
+	}
+
+	set = set.normalize()
+	if negative {
+		set = set.complement()
+	}
+	if sub != nil {
+		set = set.subtract(sub)
+	}
+	return set, nil
+}
+
+// classItem parses a single character, a range or an escape inside of
+// character class
+func (p *patternParser) classItem() (runeSet, error) {
+	lo, single, err := p.classChar()
+	if err != nil || !single {
+		return lo, err
+	}
+
+	// '-' is a range only between two characters
+	if p.peek(0) != '-' || p.peek(1) == ']' || p.peek(1) == '[' || p.pos+1 >= len(p.src) {
+		return lo, nil
+	}
+	p.pos++
+
+	hi, single, err := p.classChar()
+	if err != nil {
+		return nil, err
+	}
+	if !single {
+		return nil, p.errorf("range bound must be a single character")
+	}
+	if hi[0].lo < lo[0].lo {
+		return nil, p.errorf("invalid range %c-%c", lo[0].lo, hi[0].lo)
+	}
+	return runeSet{{lo[0].lo, hi[0].lo}}, nil
+}
+
+func (p *patternParser) classChar() (runeSet, bool, error) {
+	c := p.src[p.pos]
+	if c != '\\' {
+		p.pos++
+		return runeSet{{c, c}}, true, nil
+	}
+
+	multi := strings.ContainsRune("sSiIcCdDwWpP", p.peek(1))
+	set, err := p.escape()
+	return set, !multi, err
+}
+
+// runeRange is an inclusive range of code points
+type runeRange struct {
+	lo, hi rune
+}
+
+// runeSet is a set of code points used to evaluate character classes
+type runeSet []runeRange
+
+var spaceChars = runeSet{{'\t', '\n'}, {'\r', '\r'}, {' ', ' '}}
+
+// wordChars is the complement of \w: punctuation, separators and other
+// characters
+func wordChars() runeSet {
+	var set runeSet
+	for _, t := range []*unicode.RangeTable{unicode.P, unicode.Z, unicode.C} {
+		set = append(set, tableSet(t)...)
+	}
+	return set.normalize()
+}
+
+func tableSet(t *unicode.RangeTable) runeSet {
+	var set runeSet
+	for _, r := range t.R16 {
+		set = appendStride(set, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range t.R32 {
+		set = appendStride(set, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return set.normalize()
+}
+
+func appendStride(set runeSet, lo, hi, stride rune) runeSet {
+	if stride == 1 {
+		return append(set, runeRange{lo, hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		set = append(set, runeRange{r, r})
+	}
+	return set
+}
+
+// normalize sorts ranges and merges overlapping and adjacent ones
+func (s runeSet) normalize() runeSet {
+	if len(s) == 0 {
+		return s
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].lo < s[j].lo })
+
+	res := s[:1]
+	for _, r := range s[1:] {
+		last := &res[len(res)-1]
+		if r.lo <= last.hi+1 {
+			if r.hi > last.hi {
+				last.hi = r.hi
+			}
+			continue
+		}
+		res = append(res, r)
+	}
+	return res
+}
+
+// complement returns all code points not in the normalized set
+func (s runeSet) complement() runeSet {
+	var res runeSet
+	next := rune(0)
+	for _, r := range s {
+		if r.lo > next {
+			res = append(res, runeRange{next, r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= unicode.MaxRune {
+		res = append(res, runeRange{next, unicode.MaxRune})
+	}
+	return res
+}
+
+// subtract returns code points of the set which are not in other
+func (s runeSet) subtract(other runeSet) runeSet {
+	var res runeSet
+	exclude := other.complement()
+	for i, j := 0, 0; i < len(s) && j < len(exclude); {
+		lo, hi := s[i].lo, s[i].hi
+		if exclude[j].lo > lo {
+			lo = exclude[j].lo
+		}
+		if exclude[j].hi < hi {
+			hi = exclude[j].hi
+		}
+		if lo <= hi {
+			res = append(res, runeRange{lo, hi})
+		}
+		if s[i].hi < exclude[j].hi {
+			i++
+		} else {
+			j++
+		}
+	}
+	return res
+}
+
+// String returns RE2 character class matching the set
+func (s runeSet) String() string {
+	if len(s) == 1 && s[0].lo == s[0].hi {
+		return regexp.QuoteMeta(string(s[0].lo))
+	}
+	if len(s) == 0 {
+		// matches nothing
+		return `[^\x00-\x{10FFFF}]`
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for _, r := range s {
+		b.WriteString(classRune(r.lo))
+		if r.hi > r.lo {
+			if r.hi > r.lo+1 {
+				b.WriteByte('-')
+			}
+			b.WriteString(classRune(r.hi))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func classRune(r rune) string {
+	if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return string(r)
+	}
+	return fmt.Sprintf(`\x{%X}`, r)
+}
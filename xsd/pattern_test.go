@@ -0,0 +1,72 @@
+package xsd
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		match   bool
+	}{
+		{`\d{3}`, "123", true},
+		{`\d{3}`, "1234", false},
+		{`\p{IsBasicLatin}+`, "abc", true},
+		{`\p{IsBasicLatin}+`, "abé", false},
+		{`\P{IsBasicLatin}`, "я", true},
+		{`\p{IsCyrillic}+`, "тест", true},
+		{`\p{IsCyrillic}+`, "test", false},
+		{`[\p{IsGreek}a]+`, "αβa", true},
+		{`[a-z-[aeiou]]+`, "bcd", true},
+		{`[a-z-[aeiou]]+`, "bad", false},
+		{`[^a-c-[x]]`, "x", false},
+		{`[^a-c-[x]]`, "d", true},
+		{`[\w-[\d]]+`, "abc", true},
+		{`[\w-[\d]]+`, "a1", false},
+		{`\i\c*`, "_a1.b-c", true},
+		{`\i\c*`, "1a", false},
+		{`\i\c*`, "a b", false},
+		{`[\i-[:]][\c-[:]]*`, "ns:a", false},
+		{`^a$`, "^a$", true},
+		{`.`, "\n", false},
+		{`a-b`, "a-b", true},
+	}
+	for _, tt := range tests {
+		match, err := MatchPattern(tt.pattern, tt.value)
+		if err != nil {
+			t.Errorf("MatchPattern(%q, %q): %v", tt.pattern, tt.value, err)
+			continue
+		}
+		if match != tt.match {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.value, match, tt.match)
+		}
+	}
+}
+
+func TestTranslatePatternErrors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		offset  int
+	}{
+		{`\p{IsHighSurrogates}`, 2},
+		{`\p{IsUnknownBlock}`, 2},
+		{`\p{IsBasicLatin`, 2},
+		{`(?i)a`, 0},
+		{`[a-[b]c]`, 6},
+		{`[a`, 2},
+		{`[]`, 1},
+		{`\q`, 1},
+		{`a\`, 2},
+		{`[z-a]`, 4},
+	}
+	for _, tt := range tests {
+		_, err := TranslatePattern(tt.pattern)
+		perr, ok := err.(*PatternError)
+		if !ok {
+			t.Errorf("TranslatePattern(%q) error = %v, want PatternError", tt.pattern, err)
+			continue
+		}
+		if perr.Offset != tt.offset {
+			t.Errorf("TranslatePattern(%q) offset = %d, want %d: %v", tt.pattern, perr.Offset, tt.offset, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package xsd
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// OpenPlugin opens the plugin built by parsexsd and returns its registry
+// checked against the range, see TypeRegistry.Check. The plugin must be
+// built with the same version of this package as the host.
+func OpenPlugin(name string, accept VersionRange) (*TypeRegistry, error) {
+	p, err := plugin.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(RegistrySymbol)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := sym.(*TypeRegistry)
+	if !ok {
+		return nil, fmt.Errorf("%s of plugin %s is %T, not xsd.TypeRegistry", RegistrySymbol, name, sym)
+	}
+	if err := r.Check(accept); err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", name, err)
+	}
+	return r, nil
+}
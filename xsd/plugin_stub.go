@@ -0,0 +1,10 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package xsd
+
+import "errors"
+
+// OpenPlugin reports that plugins are not supported by the build
+func OpenPlugin(name string, accept VersionRange) (*TypeRegistry, error) {
+	return nil, errors.New("plugins are not supported on this platform or without cgo")
+}
@@ -0,0 +1,123 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RegistryContract is the version of the TypeRegistry contract of plugins,
+// it changes with incompatible changes of TypeRegistry
+const RegistryContract = 1
+
+// RegistrySymbol is the name of the TypeRegistry variable exported by
+// plugins
+const RegistrySymbol = "Registry"
+
+// PluginFile is the name of plugins in directories of versions of plugin
+// repositories
+const PluginFile = "export.so"
+
+// TypeRegistry maps root elements of schemas to factories of their structs.
+// It is generated into plugins as the variable named RegistrySymbol.
+type TypeRegistry struct {
+	// Contract is RegistryContract of the generator of the plugin
+	Contract int
+	// Version is the version of the schemas, nil if it is not known
+	Version Version
+	// Elements returns pointers to new structs of root elements
+	Elements map[xml.Name]func() interface{}
+}
+
+// Names returns sorted names of root elements
+func (r *TypeRegistry) Names() []xml.Name {
+	names := make([]xml.Name, 0, len(r.Elements))
+	for name := range r.Elements {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Space != names[j].Space {
+			return names[i].Space < names[j].Space
+		}
+		return names[i].Local < names[j].Local
+	})
+	return names
+}
+
+// New returns pointer to a new struct of the root element. Elements without
+// namespace are matched by their local names if they are unique.
+func (r *TypeRegistry) New(name xml.Name) (interface{}, error) {
+	if f, ok := r.Elements[name]; ok {
+		return f(), nil
+	}
+
+	if name.Space == "" {
+		var found func() interface{}
+		for n, f := range r.Elements {
+			if n.Local != name.Local {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("element %s is ambiguous, its namespace is needed", name.Local)
+			}
+			found = f
+		}
+		if found != nil {
+			return found(), nil
+		}
+	}
+	return nil, fmt.Errorf("no %s element in the registry of version %s", name.Local, r.Version)
+}
+
+// Decode decodes the document into the struct of its root element
+func (r *TypeRegistry) Decode(rd io.Reader) (interface{}, error) {
+	return DecodeVersioned(rd, "", func(name xml.Name, _ Version) (interface{}, error) {
+		return r.New(name)
+	})
+}
+
+// Check returns error if the registry is generated by an incompatible
+// generator or its version is out of the range. Registries without the
+// version are accepted only by empty ranges.
+func (r *TypeRegistry) Check(accept VersionRange) error {
+	if r.Contract != RegistryContract {
+		return fmt.Errorf("registry contract %d is not supported, %d is expected", r.Contract, RegistryContract)
+	}
+	if r.Version == nil {
+		if accept.Min != nil || accept.Max != nil {
+			return fmt.Errorf("registry has no version, %s is expected", accept)
+		}
+		return nil
+	}
+	if !accept.Contains(r.Version) {
+		return fmt.Errorf("registry version %s is out of %s", r.Version, accept)
+	}
+	return nil
+}
+
+// FindPlugin returns the plugin of the latest version of the range in the
+// repository of versioned plugin directories, like 6.4/export.so
+func FindPlugin(repository string, accept VersionRange) (string, error) {
+	entries, err := os.ReadDir(repository)
+	if err != nil {
+		return "", err
+	}
+
+	var latest Version
+	for _, e := range entries {
+		v, err := ParseVersion(e.Name())
+		if err != nil || !e.IsDir() || !accept.Contains(v) || (latest != nil && !latest.Less(v)) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(repository, e.Name(), PluginFile)); err == nil {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no plugin of %s in %s", accept, repository)
+	}
+	return filepath.Join(repository, latest.String(), PluginFile), nil
+}
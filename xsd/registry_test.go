@@ -0,0 +1,129 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testExport struct{ Space string }
+
+func TestTypeRegistryNew(t *testing.T) {
+	factory := func(space string) func() interface{} {
+		return func() interface{} { return &testExport{space} }
+	}
+	r := TypeRegistry{Contract: RegistryContract, Elements: map[xml.Name]func() interface{}{
+		{Space: "urn:a", Local: "export"}: factory("urn:a"),
+		{Space: "urn:b", Local: "export"}: factory("urn:b"),
+		{Space: "urn:a", Local: "notice"}: factory("urn:a"),
+	}}
+
+	tests := []struct {
+		name  xml.Name
+		space string
+		err   string
+	}{
+		{xml.Name{Space: "urn:b", Local: "export"}, "urn:b", ""},
+		{xml.Name{Local: "notice"}, "urn:a", ""},
+		{xml.Name{Local: "export"}, "", "ambiguous"},
+		{xml.Name{Space: "urn:c", Local: "notice"}, "", "no notice element"},
+		{xml.Name{Local: "protocol"}, "", "no protocol element"},
+	}
+	for _, tt := range tests {
+		v, err := r.New(tt.name)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("New(%v) error = %v, want %s", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("New(%v): %v", tt.name, err)
+			continue
+		}
+		if e, ok := v.(*testExport); !ok || e.Space != tt.space {
+			t.Errorf("New(%v) = %#v, want the struct of %s", tt.name, v, tt.space)
+		}
+	}
+}
+
+func TestTypeRegistryCheck(t *testing.T) {
+	v64, _ := ParseVersion("6.4")
+	tests := []struct {
+		name     string
+		registry TypeRegistry
+		accept   string
+		ok       bool
+	}{
+		{"in range", TypeRegistry{Contract: RegistryContract, Version: v64}, "6.4 <= v < 7", true},
+		{"out of range", TypeRegistry{Contract: RegistryContract, Version: v64}, "v < 6.4", false},
+		{"any version", TypeRegistry{Contract: RegistryContract, Version: v64}, "", true},
+		{"no version", TypeRegistry{Contract: RegistryContract}, "", true},
+		{"no version of range", TypeRegistry{Contract: RegistryContract}, ">= 6", false},
+		{"other contract", TypeRegistry{Contract: RegistryContract + 1, Version: v64}, "", false},
+	}
+	for _, tt := range tests {
+		var accept VersionRange
+		if tt.accept != "" {
+			var err error
+			if accept, err = ParseVersionRange(tt.accept); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tt.registry.Check(accept); (err == nil) != tt.ok {
+			t.Errorf("%s: Check(%s) = %v, want ok %v", tt.name, tt.accept, err, tt.ok)
+		}
+	}
+}
+
+func TestFindPlugin(t *testing.T) {
+	repository := t.TempDir()
+	for _, dir := range []string{"4.4", "6.4", "6.10", "7.0", "latest"} {
+		if err := os.MkdirAll(filepath.Join(repository, dir), 0777); err != nil {
+			t.Fatal(err)
+		}
+		// 7.0 is not built yet
+		if dir == "7.0" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(repository, dir, PluginFile), nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// files are not versions
+	if err := os.WriteFile(filepath.Join(repository, "8.0"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		accept string
+		dir    string
+	}{
+		{"", "6.10"},
+		{"v < 6.10", "6.4"},
+		{"4.4", "4.4"},
+		{"v >= 7", ""},
+		{"v < 4", ""},
+	}
+	for _, tt := range tests {
+		var accept VersionRange
+		if tt.accept != "" {
+			var err error
+			if accept, err = ParseVersionRange(tt.accept); err != nil {
+				t.Fatal(err)
+			}
+		}
+		name, err := FindPlugin(repository, accept)
+		if tt.dir == "" {
+			if err == nil {
+				t.Errorf("FindPlugin(%s) = %s, want an error", tt.accept, name)
+			}
+			continue
+		}
+		if want := filepath.Join(repository, tt.dir, PluginFile); err != nil || name != want {
+			t.Errorf("FindPlugin(%s) = %s, %v, want %s", tt.accept, name, err, want)
+		}
+	}
+}
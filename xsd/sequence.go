@@ -39,5 +39,9 @@ func (s Sequence) GetAllElements() []Element {
 			elements = append(elements, ss.GetAllElements()...)
 		}
 	}
+
+	if s.Min == "0" {
+		return optional(elements)
+	}
 	return elements
 }
@@ -0,0 +1,397 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version represents slice of version numbers from major to minor. Missing
+// minor numbers are zeros, so 6.4 and 6.4.0 are equal. Version is
+// marshalled to text and JSON as a string like 6.4.
+type Version []int
+
+// ParseVersion parses versions like 6.4 or 4.4.0
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	version := make(Version, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		version[i] = n
+	}
+	return version, nil
+}
+
+// String implements of Stringer interface
+func (v Version) String() string {
+	res := ""
+	if len(v) == 0 {
+		return res
+	}
+
+	for i, val := range v {
+		if i < len(v)-1 {
+			res += strconv.Itoa(val) + "."
+		}
+	}
+
+	res += strconv.Itoa(v[len(v)-1])
+	return res
+}
+
+// Compare returns -1 if v is less than other, 1 if it is greater and 0 if
+// they are equal
+func (v Version) Compare(other Version) int {
+	for i := 0; i < len(v) || i < len(other); i++ {
+		var a, b int
+		if i < len(v) {
+			a = v[i]
+		}
+		if i < len(other) {
+			b = other[i]
+		}
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Less reports whether v is less than other
+func (v Version) Less(other Version) bool {
+	return v.Compare(other) < 0
+}
+
+// Equal reports whether v equals other
+func (v Version) Equal(other Version) bool {
+	return v.Compare(other) == 0
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, empty text is nil
+// version
+func (v *Version) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*v = nil
+		return nil
+	}
+
+	version, err := ParseVersion(string(text))
+	if err != nil {
+		return err
+	}
+	*v = version
+	return nil
+}
+
+// VersionRange is a range of versions, bounds which are nil are open
+type VersionRange struct {
+	Min, Max                   Version
+	MinExclusive, MaxExclusive bool
+}
+
+// versionRangeToken splits ranges into versions, operators and the v
+// placeholder
+var versionRangeToken = regexp.MustCompile(`^\s*(<=|>=|==|<|>|=|,|v|\d+(?:\.\d+)*)\s*`)
+
+// ParseVersionRange parses ranges like "6.4 <= v < 7", ">=6.4, <7" or
+// "6.4", which is the single version
+func ParseVersionRange(s string) (VersionRange, error) {
+	var tokens []string
+	for rest := s; strings.TrimSpace(rest) != ""; {
+		m := versionRangeToken.FindStringSubmatch(rest)
+		if m == nil {
+			return VersionRange{}, fmt.Errorf("invalid version range %q", s)
+		}
+		tokens = append(tokens, m[1])
+		rest = rest[len(m[0]):]
+	}
+	if len(tokens) == 0 {
+		return VersionRange{}, fmt.Errorf("empty version range")
+	}
+
+	var r VersionRange
+	for i := 0; i < len(tokens); i++ {
+		switch tok := tokens[i]; {
+		case tok == "v" || tok == ",":
+		case isVersionOperator(tok):
+			if i+1 == len(tokens) {
+				return VersionRange{}, fmt.Errorf("invalid version range %q", s)
+			}
+			version, err := ParseVersion(tokens[i+1])
+			if err != nil {
+				return VersionRange{}, fmt.Errorf("invalid version range %q", s)
+			}
+			r.bound(tok, version)
+			i++
+		default:
+			version, err := ParseVersion(tok)
+			if err != nil {
+				return VersionRange{}, fmt.Errorf("invalid version range %q", s)
+			}
+			// 6.4 <= v is the bound of v with the reversed operator
+			if i+2 < len(tokens) && isVersionOperator(tokens[i+1]) && tokens[i+2] == "v" {
+				r.bound(reverseVersionOperator(tokens[i+1]), version)
+				i++
+				continue
+			}
+			r.bound("=", version)
+		}
+	}
+	return r, nil
+}
+
+// bound sets the bound of v given by the operator
+func (r *VersionRange) bound(op string, version Version) {
+	switch op {
+	case ">", ">=":
+		r.Min, r.MinExclusive = version, op == ">"
+	case "<", "<=":
+		r.Max, r.MaxExclusive = version, op == "<"
+	default:
+		r.Min, r.Max = version, version
+		r.MinExclusive, r.MaxExclusive = false, false
+	}
+}
+
+func isVersionOperator(tok string) bool {
+	switch tok {
+	case "<=", ">=", "==", "<", ">", "=":
+		return true
+	}
+	return false
+}
+
+func reverseVersionOperator(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// Contains reports whether the version is in the range
+func (r VersionRange) Contains(v Version) bool {
+	if r.Min != nil {
+		if c := v.Compare(r.Min); c < 0 || (c == 0 && r.MinExclusive) {
+			return false
+		}
+	}
+	if r.Max != nil {
+		if c := v.Compare(r.Max); c > 0 || (c == 0 && r.MaxExclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the range like "6.4 <= v < 7"
+func (r VersionRange) String() string {
+	if r.Min != nil && r.Max != nil && r.Min.Equal(r.Max) && !r.MinExclusive && !r.MaxExclusive {
+		return r.Min.String()
+	}
+
+	var parts []string
+	if r.Min != nil {
+		op := "<="
+		if r.MinExclusive {
+			op = "<"
+		}
+		parts = append(parts, r.Min.String(), op)
+	}
+	parts = append(parts, "v")
+	if r.Max != nil {
+		op := "<="
+		if r.MaxExclusive {
+			op = "<"
+		}
+		parts = append(parts, op, r.Max.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r VersionRange) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *VersionRange) UnmarshalText(text []byte) error {
+	parsed, err := ParseVersionRange(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// VersionDetector detects version of the schema
+type VersionDetector interface {
+	DetectVersion(s *Schema) (Version, bool)
+}
+
+// DefaultVersionPattern matches versions given in comments like
+// <!-- FCS INTEGRATION_TYPES Integration Scheme, version 4.4.0, create date 21.07.2014 -->
+var DefaultVersionPattern = regexp.MustCompile(`version (\d+(?:\.\d+)*)`)
+
+// DefaultVersionDetectors are detectors used by Loader by default
+var DefaultVersionDetectors = []VersionDetector{
+	VersionAttribute{},
+	VersionFixedAttribute{Name: "schemeVersion"},
+	VersionComment{},
+}
+
+// VersionAttribute detects the version by the version attribute of the
+// schema element
+type VersionAttribute struct{}
+
+// DetectVersion implements VersionDetector
+func (VersionAttribute) DetectVersion(s *Schema) (Version, bool) {
+	return parseDetected(s.VersionAttr)
+}
+
+// VersionComment detects the version by the first capture group of the
+// pattern in comments before and inside of the schema element, the pattern
+// is DefaultVersionPattern if it is nil
+type VersionComment struct {
+	Pattern *regexp.Regexp
+}
+
+// DetectVersion implements VersionDetector
+func (c VersionComment) DetectVersion(s *Schema) (Version, bool) {
+	re := c.Pattern
+	if re == nil {
+		re = DefaultVersionPattern
+	}
+	comments := append(append([]string(nil), s.Prolog...), s.Comment)
+	for _, comment := range comments {
+		if m := re.FindStringSubmatch(comment); len(m) > 1 {
+			if v, ok := parseDetected(m[1]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// VersionFixedAttribute detects the version by the fixed value of the
+// attribute with the name, like schemeVersion of zakupki.gov.ru documents
+type VersionFixedAttribute struct {
+	Name string
+}
+
+// DetectVersion implements VersionDetector
+func (a VersionFixedAttribute) DetectVersion(s *Schema) (Version, bool) {
+	for i := range s.ComplexTypes {
+		if v, ok := a.inType(&s.ComplexTypes[i]); ok {
+			return v, true
+		}
+	}
+	for _, e := range s.Elements {
+		if e.ComplexType != nil {
+			if v, ok := a.inType(e.ComplexType); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (a VersionFixedAttribute) inType(ct *ComplexType) (Version, bool) {
+	attrs := ct.Attributes
+	if ct.ComplexContent != nil && ct.ComplexContent.Extension != nil {
+		attrs = append(attrs, ct.ComplexContent.Extension.Attributes...)
+	}
+	if ct.SimpleContent != nil && ct.SimpleContent.Extension != nil {
+		attrs = append(attrs, ct.SimpleContent.Extension.Attributes...)
+	}
+	for _, attr := range attrs {
+		if attr.Name == a.Name && attr.Fixed != "" {
+			if v, ok := parseDetected(attr.Fixed); ok {
+				return v, true
+			}
+		}
+	}
+
+	for _, e := range ct.GetAllElements() {
+		if e.ComplexType != nil {
+			if v, ok := a.inType(e.ComplexType); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// FixedVersion is the version given explicitly, it is detected for every
+// schema
+type FixedVersion Version
+
+// DetectVersion implements VersionDetector
+func (v FixedVersion) DetectVersion(*Schema) (Version, bool) {
+	return Version(v), len(v) > 0
+}
+
+// DetectVersion returns the version found by the first detector which
+// detects it, nil if none does
+func DetectVersion(s *Schema, detectors ...VersionDetector) Version {
+	for _, d := range detectors {
+		if v, ok := d.DetectVersion(s); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func parseDetected(s string) (Version, bool) {
+	if s == "" {
+		return nil, false
+	}
+	v, err := ParseVersion(s)
+	return v, err == nil
+}
+
+// decodeSchema decodes the schema and comments before its root element
+func decodeSchema(d *xml.Decoder) (*Schema, error) {
+	var prolog []string
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no schema element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := t.(type) {
+		case xml.Comment:
+			prolog = append(prolog, string(t))
+		case xml.StartElement:
+			s := new(Schema)
+			if err := d.DecodeElement(s, &t); err != nil {
+				return nil, err
+			}
+			s.Prolog = prolog
+			return s, nil
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package xsd
+
+import "testing"
+
+func TestVersionRangeContains(t *testing.T) {
+	tests := []struct {
+		rng      string
+		str      string
+		contains []string
+		excludes []string
+	}{
+		{"6.4 <= v < 7", "6.4 <= v < 7", []string{"6.4", "6.4.0", "6.5", "6.99.1"}, []string{"6.3.9", "7", "7.0.1"}},
+		{">=6.4, <7", "6.4 <= v < 7", []string{"6.4", "6.9"}, []string{"6.3", "7"}},
+		{"6.4 < v <= 7", "6.4 < v <= 7", []string{"6.4.1", "7", "7.0"}, []string{"6.4", "7.0.1"}},
+		{"v > 6.4", "6.4 < v", []string{"6.5", "10"}, []string{"6.4", "1"}},
+		{"v <= 6", "v <= 6", []string{"1", "6", "6.0"}, []string{"6.0.1", "7"}},
+		{"7 > v", "v < 7", []string{"6.9"}, []string{"7"}},
+		{"6.4", "6.4", []string{"6.4", "6.4.0"}, []string{"6.4.1", "6.3"}},
+		{"== 6.4", "6.4", []string{"6.4"}, []string{"6.5"}},
+	}
+	for _, tt := range tests {
+		r, err := ParseVersionRange(tt.rng)
+		if err != nil {
+			t.Errorf("ParseVersionRange(%q): %v", tt.rng, err)
+			continue
+		}
+		if r.String() != tt.str {
+			t.Errorf("ParseVersionRange(%q) = %s, want %s", tt.rng, r, tt.str)
+		}
+		for _, s := range tt.contains {
+			if v, _ := ParseVersion(s); !r.Contains(v) {
+				t.Errorf("%s does not contain %s", tt.rng, s)
+			}
+		}
+		for _, s := range tt.excludes {
+			if v, _ := ParseVersion(s); r.Contains(v) {
+				t.Errorf("%s contains %s", tt.rng, s)
+			}
+		}
+	}
+}
+
+func TestParseVersionRangeErrors(t *testing.T) {
+	for _, s := range []string{"", "  ", "<", "6.4 <=", "v < x", "6.a", ">= -1", "6.4 ~ 7"} {
+		if r, err := ParseVersionRange(s); err == nil {
+			t.Errorf("ParseVersionRange(%q) = %s, want an error", s, r)
+		}
+	}
+}
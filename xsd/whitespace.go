@@ -0,0 +1,53 @@
+package xsd
+
+import "strings"
+
+// Values of the whiteSpace facet
+// https://www.w3.org/TR/xmlschema11-2/#rf-whiteSpace
+const (
+	WhiteSpacePreserve = "preserve"
+	WhiteSpaceReplace  = "replace"
+	WhiteSpaceCollapse = "collapse"
+)
+
+var whiteSpaceReplacer = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+// NormalizeWhiteSpace applies whiteSpace facet semantics to the value:
+// replace turns every tab, line feed and carriage return into a space,
+// collapse additionally squeezes runs of spaces and trims the value.
+func NormalizeWhiteSpace(value, mode string) string {
+	switch mode {
+	case WhiteSpaceReplace:
+		return whiteSpaceReplacer.Replace(value)
+	case WhiteSpaceCollapse:
+		return strings.Join(strings.FieldsFunc(value, isXMLSpace), " ")
+	default:
+		return value
+	}
+}
+
+func isXMLSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// NormalizedString represents xs:normalizedString and any string restriction
+// with whiteSpace="replace"
+// https://www.w3.org/TR/xmlschema11-2/#normalizedString
+type NormalizedString string
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *NormalizedString) UnmarshalText(text []byte) error {
+	*s = NormalizedString(NormalizeWhiteSpace(string(text), WhiteSpaceReplace))
+	return nil
+}
+
+// Token represents xs:token, its derived types and any string restriction
+// with whiteSpace="collapse"
+// https://www.w3.org/TR/xmlschema11-2/#token
+type Token string
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (t *Token) UnmarshalText(text []byte) error {
+	*t = Token(NormalizeWhiteSpace(string(text), WhiteSpaceCollapse))
+	return nil
+}
@@ -1,26 +1,55 @@
 package xsd
 
 import (
-	"bufio"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
-	"strconv"
 	"strings"
 )
 
 // Schema is the root of our Go representation of an XSD schema.
 // http://www.w3schools.com/xml/el_schema.asp
 type Schema struct {
-	XMLName      xml.Name
-	Ns           string        `xml:"xmlns,attr"`
-	Comment      string        `xml:",comment"`
-	Imports      []Import      `xml:"import"`
-	Elements     []Element     `xml:"element"`
-	ComplexTypes []ComplexType `xml:"complexType"`
-	SimpleTypes  []SimpleType  `xml:"simpleType"`
-	Version      Version
+	XMLName         xml.Name
+	Ns              string        `xml:"xmlns,attr"`
+	TargetNamespace string        `xml:"targetNamespace,attr"`
+	Comment         string        `xml:",comment"`
+	VersionAttr     string        `xml:"version,attr"`
+	Imports         []Import      `xml:"import"`
+	Includes        []Import      `xml:"include"`
+	Elements        []Element     `xml:"element"`
+	ComplexTypes    []ComplexType `xml:"complexType"`
+	SimpleTypes     []SimpleType  `xml:"simpleType"`
+	// Attrs are other attributes of the schema element, including namespace
+	// declarations
+	Attrs []xml.Attr `xml:",any,attr"`
+	// Version is detected by Loader, see VersionDetector
+	Version Version `xml:"-"`
+	// Prolog are comments before the schema element
+	Prolog []string `xml:"-"`
+	// Location is the absolute URI the schema is read from by Loader
+	Location string `xml:"-"`
+	// Hash is the hex SHA-256 of the file the schema is read from by Loader
+	Hash string `xml:"-"`
+	// Lines are lines of top-level components by their kinds and names,
+	// like "complexType zfcs_contractType", they are read by Loader
+	Lines map[string]int `xml:"-"`
+}
+
+// Namespace returns the namespace the prefix is declared for by the schema
+// element, empty prefix is the default namespace
+func (s Schema) Namespace(prefix string) string {
+	if prefix == "" {
+		return s.Ns
+	}
+	for _, a := range s.Attrs {
+		if a.Name.Space == "xmlns" && a.Name.Local == prefix {
+			return a.Value
+		}
+	}
+	return ""
 }
 
 //GetSchemaVersion parse file and returns version of xsd
@@ -28,47 +57,40 @@ func GetSchemaVersion(fname string) (Version, error) {
 	//<!-- FCS INTEGRATION_TYPES Integration Scheme, version 4.4.0, create date 21.07.2014 -->
 	f, err := os.Open(fname)
 	if err != nil {
-		return nil, fmt.Errorf("Не удалось получить версию схемы: %s\n%v", fname, err)
+		return nil, fmt.Errorf("could not read version of schema %s: %v", fname, err)
 	}
 	defer f.Close()
-	reader := bufio.NewReader(f)
 
-	// comment with version must be on second line
-	reader.ReadString('\n')
-	comment, err := reader.ReadString('\n')
+	version, err := ReadSchemaVersion(f)
 	if err != nil {
-		return nil, fmt.Errorf("Не удалось получить версию схемы: %s\n%v", fname, err)
+		return nil, fmt.Errorf("could not read version of schema %s: %v", fname, err)
 	}
+	return version, nil
+}
 
-	// println("comment is: ", comment)
-	if strings.HasPrefix(comment, "<!--") {
-		re := regexp.MustCompile(`version ([\d+\.?]+)`)
-		m := re.FindStringSubmatch(comment)
-		if len(m) != 2 {
-			return nil, fmt.Errorf("Схема версии не указана")
-		}
-
-		splitMatch := strings.Split(m[1], ".")
-		version := make([]int, len(splitMatch))
-		for i, val := range splitMatch {
-			intVal, err := strconv.Atoi(strings.TrimSpace(val))
-			if err != nil {
-				return nil, fmt.Errorf("Неправильный формат версии xsd")
-			}
-			version[i] = intVal
-		}
+// ReadSchemaVersion reads version of xsd by the detectors, by
+// DefaultVersionDetectors if none are given
+func ReadSchemaVersion(r io.Reader, detectors ...VersionDetector) (Version, error) {
+	if len(detectors) == 0 {
+		detectors = DefaultVersionDetectors
+	}
 
-		return version, nil
+	s, err := decodeSchema(NewDecoder(r))
+	if err != nil {
+		return nil, err
+	}
+	version := DetectVersion(s, detectors...)
+	if version == nil {
+		return nil, errors.New("version of the schema is not found")
 	}
-	return nil, fmt.Errorf("Version could not be found")
+	return version, nil
 }
 
-// Version represents slice of version numbers from major to minor
-type Version []int
-
-// Import http://www.w3schools.com/xml/el_import.asp
+// Import http://www.w3schools.com/xml/el_import.asp, also used for
+// http://www.w3schools.com/xml/el_include.asp
 type Import struct {
-	Location string `xml:"schemaLocation,attr"`
+	Namespace string `xml:"namespace,attr"`
+	Location  string `xml:"schemaLocation,attr"`
 }
 
 // NS parses the namespace from a value in the expected format
@@ -81,23 +103,6 @@ func (s Schema) NS() string {
 	return ""
 }
 
-// String implements of Stringer interface
-func (v Version) String() string {
-	res := ""
-	if len(v) == 0 {
-		return res
-	}
-
-	for i, val := range v {
-		if i < len(v)-1 {
-			res += strconv.Itoa(val) + "."
-		}
-	}
-
-	res += strconv.Itoa(v[len(v)-1])
-	return res
-}
-
 // HavingMaxOccurs represent types which contains MaxOccurs attribute
 type HavingMaxOccurs interface {
 	MaxOccurs() string
@@ -108,6 +113,15 @@ func IsList(hmo HavingMaxOccurs) bool {
 	return hmo.MaxOccurs() == "unbounded"
 }
 
+// optional marks elements of a compositor which may be absent as a whole,
+// such as alternatives of a choice, with minOccurs="0"
+func optional(elements []Element) []Element {
+	for i := range elements {
+		elements[i].Min = "0"
+	}
+	return elements
+}
+
 // ComplexContent http://www.w3schools.com/xml/el_complexcontent.asp
 type ComplexContent struct {
 	Extension   *Extension   `xml:"extension"`
@@ -132,6 +146,7 @@ type Attribute struct {
 	Name       string `xml:"name,attr"`
 	Type       string `xml:"type,attr"`
 	Use        string `xml:"use,attr"`
+	Fixed      string `xml:"fixed,attr"`
 	Annotation string `xml:"annotation>documentation"`
 }
 
@@ -145,8 +160,18 @@ type SimpleType struct {
 // Restriction http://www.w3schools.com/xml/el_restriction.asp
 type Restriction struct {
 	Base        string        `xml:"base,attr"`
-	Pattern     Pattern       `xml:"pattern"`
+	Patterns    []Pattern     `xml:"pattern"`
 	Enumeration []Enumeration `xml:"enumeration"`
+	WhiteSpace  Facet         `xml:"whiteSpace"`
+	Length      Facet         `xml:"length"`
+	MinLength   Facet         `xml:"minLength"`
+	MaxLength   Facet         `xml:"maxLength"`
+}
+
+// Facet is a constraining facet with a single value attribute
+// https://www.w3.org/TR/xmlschema11-2/#rf-facets
+type Facet struct {
+	Value string `xml:"value,attr"`
 }
 
 // Pattern http://www.w3schools.com/xml/schema_elements_ref.asp